@@ -0,0 +1,81 @@
+// Package config centralizes the framework names, listen addresses and pid
+// lookups shared between the frameworks/* servers and the mwsbench runner.
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Framework names. Add one whenever a new frameworks/* package is wired up.
+const (
+	NbioStd      = "nbio_std"
+	NbioModMixed = "nbio_mixed"
+	// Fake is the frameworks/fake pseudo-framework: an in-process
+	// websocket echo server with no real socket, used as a ceiling to
+	// normalize the other frameworks' numbers against. mwsbench special-
+	// cases it to dial in-process instead of a real address, so its
+	// frameworkPorts entry is never actually listened on.
+	Fake = "fake"
+)
+
+// frameworkPorts maps a framework name to the TCP ports its server(s)
+// listen on. Extend this map alongside the frameworks/* package.
+var frameworkPorts = map[string][]int{
+	NbioStd:      {8001},
+	NbioModMixed: {8002},
+	Fake:         {0},
+}
+
+// GetFrameworkServerAddrs returns the addresses a framework's server(s)
+// listen on, suitable for both nbhttp.Config.Addrs and the mwsbench dialer.
+func GetFrameworkServerAddrs(framework string) ([]string, error) {
+	ports, ok := frameworkPorts[framework]
+	if !ok {
+		return nil, fmt.Errorf("config: unknown framework %q", framework)
+	}
+	addrs := make([]string, 0, len(ports))
+	for _, port := range ports {
+		addrs = append(addrs, fmt.Sprintf(":%d", port))
+	}
+	return addrs, nil
+}
+
+// GetFrameworkPid asks the framework's debug endpoint (registered by
+// frameworks.HandleCommon) for its process id, so PsCounter can sample the
+// right process even when the benchmark client and the server run on the
+// same host.
+func GetFrameworkPid(framework string, ip string) (int, error) {
+	if framework == Fake {
+		// The fake server runs in the same process as mwsbench itself,
+		// so there's no /debug/pid to fetch.
+		return os.Getpid(), nil
+	}
+
+	addrs, err := GetFrameworkServerAddrs(framework)
+	if err != nil {
+		return 0, err
+	}
+	if len(addrs) == 0 {
+		return 0, fmt.Errorf("config: framework %q has no addresses", framework)
+	}
+	url := fmt.Sprintf("http://%s%s/debug/pid", ip, addrs[0])
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, fmt.Errorf("config: GetFrameworkPid(%v) failed: %w", framework, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("config: GetFrameworkPid(%v) failed: %w", framework, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil {
+		return 0, fmt.Errorf("config: GetFrameworkPid(%v) bad response %q: %w", framework, body, err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,99 @@
+// Package frameworks holds the bits shared by every frameworks/* server:
+// the common debug handlers, the listener used to accept connections, and
+// small socket helpers.
+package frameworks
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+
+	"go-websocket-benchmark/logging"
+	"go-websocket-benchmark/mwsbench/netsim"
+)
+
+var (
+	debugPprof        = flag.Bool("debugPprof", false, "register /debug/pprof/* so mwsbench can fetch a matching server-side profile")
+	debugBlockProfile = flag.Bool("debugBlockProfile", false, "also sample block profile events while -debugPprof is set; costs overhead for the server's entire lifetime, so it's opt-in separately from -debugPprof")
+	debugMutexProfile = flag.Bool("debugMutexProfile", false, "also sample mutex profile events while -debugPprof is set; costs overhead for the server's entire lifetime, so it's opt-in separately from -debugPprof")
+	networkMode       = flag.String("networkMode", "local", "simulate network conditions for accepted connections: local, lan, wan, mobile, or custom:rtt=80ms,jitter=10ms,bw=10Mbit,loss=0.1%")
+)
+
+// HandleCommon registers the HTTP endpoints every frameworks/* server
+// exposes: /debug/pid so config.GetFrameworkPid can find the server
+// process to sample with PsCounter, and, behind -debugPprof, the standard
+// /debug/pprof/* handlers so mwsbench can download a CPU/heap/block/mutex
+// profile or trace from the server under test. Block and mutex sampling
+// are further gated behind their own flags since, unlike the other
+// profiles, they add sampling overhead for the server's entire lifetime
+// rather than just while a profile is being captured.
+func HandleCommon(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pid", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%d", os.Getpid())
+	})
+
+	if *debugPprof {
+		if *debugBlockProfile {
+			runtime.SetBlockProfileRate(1)
+		}
+		if *debugMutexProfile {
+			runtime.SetMutexProfileFraction(1)
+		}
+
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/pprof/heap", pprof.Handler("heap"))
+		mux.Handle("/debug/pprof/block", pprof.Handler("block"))
+		mux.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
+		mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	}
+}
+
+// Listen is the net.Listen used by every frameworks/* server, so benchmark
+// tooling (network simulation, fake transports, ...) has a single seam to
+// wrap without touching each server package. When -networkMode requests
+// simulated WAN conditions, every Accept()ed connection is shaped
+// accordingly.
+func Listen(network, addr string) (net.Listener, error) {
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := netsim.ParseMode(*networkMode)
+	if err != nil {
+		logging.Fatalf("Listen: %v", err)
+	}
+	if !cfg.Enabled() {
+		return ln, nil
+	}
+	return &shapedListener{Listener: ln, shaper: netsim.NewShaper(cfg)}, nil
+}
+
+// shapedListener wraps every Accept()ed net.Conn with a netsim.Shaper.
+type shapedListener struct {
+	net.Listener
+	shaper *netsim.Shaper
+}
+
+func (l *shapedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.shaper.Wrap(conn), nil
+}
+
+// SetNoDelay toggles TCP_NODELAY on conn when it is a *net.TCPConn.
+func SetNoDelay(conn net.Conn, nodelay bool) {
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetNoDelay(nodelay)
+	}
+}
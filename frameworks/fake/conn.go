@@ -0,0 +1,147 @@
+// Package fake provides an in-memory net.Conn pair and a trivial
+// websocket echo server that speaks just enough of RFC 6455 to drive
+// BenchEcho/BenchRate without a real socket or even a loopback interface.
+// It exists to give the suite a ceiling to normalize framework numbers
+// against ("how fast could any client possibly go, with zero kernel/NIC
+// cost"), and, via Pipe, a way to point a fake client at a real
+// framework's server handler to isolate its encode/decode cost from the
+// network entirely.
+package fake
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ringBuffer is a fixed-capacity circular byte buffer shared by one
+// direction of a Conn pair. Writers block when it's full and readers
+// block when it's empty, each woken by a non-blocking signal on wake
+// rather than a condition variable, mirroring fasthttp's fakeConn.
+type ringBuffer struct {
+	mu     sync.Mutex
+	buf    []byte
+	r      int
+	n      int // number of unread bytes currently buffered
+	wake   chan struct{}
+	closed bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size), wake: make(chan struct{}, 1)}
+}
+
+func (rb *ringBuffer) signal() {
+	select {
+	case rb.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (rb *ringBuffer) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		rb.mu.Lock()
+		if rb.closed {
+			rb.mu.Unlock()
+			return written, io.ErrClosedPipe
+		}
+		free := len(rb.buf) - rb.n
+		if free == 0 {
+			rb.mu.Unlock()
+			<-rb.wake
+			continue
+		}
+		n := len(p) - written
+		if n > free {
+			n = free
+		}
+		writeAt := (rb.r + rb.n) % len(rb.buf)
+		for i := 0; i < n; i++ {
+			rb.buf[(writeAt+i)%len(rb.buf)] = p[written+i]
+		}
+		rb.n += n
+		written += n
+		rb.mu.Unlock()
+		rb.signal()
+	}
+	return written, nil
+}
+
+func (rb *ringBuffer) Read(p []byte) (int, error) {
+	for {
+		rb.mu.Lock()
+		if rb.n > 0 {
+			n := len(p)
+			if n > rb.n {
+				n = rb.n
+			}
+			for i := 0; i < n; i++ {
+				p[i] = rb.buf[(rb.r+i)%len(rb.buf)]
+			}
+			rb.r = (rb.r + n) % len(rb.buf)
+			rb.n -= n
+			rb.mu.Unlock()
+			rb.signal()
+			return n, nil
+		}
+		closed := rb.closed
+		rb.mu.Unlock()
+		if closed {
+			return 0, io.EOF
+		}
+		<-rb.wake
+	}
+}
+
+func (rb *ringBuffer) Close() error {
+	rb.mu.Lock()
+	rb.closed = true
+	rb.mu.Unlock()
+	rb.signal()
+	return nil
+}
+
+// addr is the net.Addr reported by a fake Conn; there's no real socket
+// behind it, so it's just a fixed label.
+type addr string
+
+func (a addr) Network() string { return "fake" }
+func (a addr) String() string  { return string(a) }
+
+// Conn is one end of an in-memory connection pair created by Pipe.
+// Deadlines are accepted but not enforced since there's no syscall to
+// time out.
+type Conn struct {
+	read  *ringBuffer
+	write *ringBuffer
+	local addr
+	peer  addr
+}
+
+func (c *Conn) Read(p []byte) (int, error)       { return c.read.Read(p) }
+func (c *Conn) Write(p []byte) (int, error)      { return c.write.Write(p) }
+func (c *Conn) Close() error                     { return c.write.Close() }
+func (c *Conn) LocalAddr() net.Addr              { return c.local }
+func (c *Conn) RemoteAddr() net.Addr             { return c.peer }
+func (c *Conn) SetDeadline(time.Time) error      { return nil }
+func (c *Conn) SetReadDeadline(time.Time) error  { return nil }
+func (c *Conn) SetWriteDeadline(time.Time) error { return nil }
+
+// bufferSize is the capacity of each direction's ring buffer. It only
+// needs to be big enough that a single echoed message doesn't deadlock
+// against a reader that hasn't drained yet; BenchEcho/BenchRate both
+// read promptly, so this is generous rather than tuned.
+const bufferSize = 256 * 1024
+
+// Pipe returns a connected in-memory net.Conn pair: client is what a
+// benchmark dials, server is what ServeEcho (or a real framework's
+// connection handler) reads from.
+func Pipe() (client, server net.Conn) {
+	clientToServer := newRingBuffer(bufferSize)
+	serverToClient := newRingBuffer(bufferSize)
+	client = &Conn{read: serverToClient, write: clientToServer, local: "fake-client", peer: "fake-server"}
+	server = &Conn{read: clientToServer, write: serverToClient, local: "fake-server", peer: "fake-client"}
+	return client, server
+}
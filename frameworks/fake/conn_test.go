@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRingBufferWraparound(t *testing.T) {
+	rb := newRingBuffer(8)
+
+	// Fill most of the buffer, drain it, then write again so the next
+	// write wraps past the end of the backing array.
+	if _, err := rb.Write([]byte("abcdef")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got := make([]byte, 4)
+	if _, err := io.ReadFull(rb, got); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(got) != "abcd" {
+		t.Fatalf("Read = %q, want %q", got, "abcd")
+	}
+
+	// r is now at index 4 with 2 unread bytes ("ef"); this write needs 6
+	// free bytes, which only exist by wrapping around past index 8.
+	if _, err := rb.Write([]byte("ghijkl")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	rest := make([]byte, 8)
+	n, err := io.ReadFull(rb, rest)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := "efghijkl"; string(rest[:n]) != want {
+		t.Fatalf("Read = %q, want %q", rest[:n], want)
+	}
+}
+
+func TestRingBufferCloseUnblocksReader(t *testing.T) {
+	rb := newRingBuffer(4)
+	done := make(chan error, 1)
+	go func() {
+		_, err := rb.Read(make([]byte, 1))
+		done <- err
+	}()
+	rb.Close()
+	if err := <-done; err != io.EOF {
+		t.Fatalf("Read after Close = %v, want io.EOF", err)
+	}
+}
+
+func TestPipeEchoesBytes(t *testing.T) {
+	client, server := Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	want := bytes.Repeat([]byte("x"), 1024)
+	go func() {
+		buf := make([]byte, len(want))
+		io.ReadFull(server, buf)
+		server.Write(buf)
+	}()
+
+	if _, err := client.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(client, got); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Read = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package fake
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// websocketGUID is the fixed string RFC 6455 section 1.3 has servers
+// append to the client's Sec-WebSocket-Key before hashing, to prove the
+// response came from a server that understood the handshake.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ServeEcho performs a minimal RFC 6455 handshake on conn and then echoes
+// every data frame it receives back unmodified, until the peer closes the
+// connection or sends a close frame. It doesn't support permessage-deflate
+// or fragmented messages: it never negotiates the extension, RSV bits are
+// ignored and not echoed back, and each frame is echoed on its own.
+func ServeEcho(conn io.ReadWriteCloser) error {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return fmt.Errorf("fake: read handshake request: %w", err)
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return fmt.Errorf("fake: handshake request missing Sec-WebSocket-Key")
+	}
+	accept := acceptKey(key)
+	if _, err := io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: "+accept+"\r\n\r\n"); err != nil {
+		return fmt.Errorf("fake: write handshake response: %w", err)
+	}
+
+	for {
+		opcode, payload, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case opcodeClose:
+			writeFrame(conn, opcodeClose, payload)
+			return nil
+		case opcodePing:
+			if err := writeFrame(conn, opcodePong, payload); err != nil {
+				return err
+			}
+		case opcodePong:
+			// no response expected
+		default:
+			if err := writeFrame(conn, opcode, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value a compliant client
+// expects back for the given Sec-WebSocket-Key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key)
+	io.WriteString(h, websocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+const (
+	opcodeText   = 1
+	opcodeBinary = 2
+	opcodeClose  = 8
+	opcodePing   = 9
+	opcodePong   = 10
+)
+
+// readFrame reads one (unfragmented) RFC 6455 frame and returns its
+// opcode and unmasked payload. Client frames are always masked; this
+// unmasks them so the caller sees the logical payload.
+func readFrame(r *bufio.Reader) (opcode int, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext))
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// writeFrame writes an unmasked RFC 6455 server-to-client frame, which
+// per the spec must never be masked.
+func writeFrame(w io.Writer, opcode int, payload []byte) error {
+	length := len(payload)
+	b0 := byte(0x80) | byte(opcode&0x0f) // fin + opcode
+
+	var header []byte
+	switch {
+	case length <= 125:
+		header = []byte{b0, byte(length)}
+	case length <= 65535:
+		header = []byte{b0, 126, byte(length >> 8), byte(length)}
+	default:
+		header = make([]byte, 10)
+		header[0] = b0
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if len(payload) > 0 {
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package fake
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func maskPayload(payload []byte, mask [4]byte) []byte {
+	out := make([]byte, len(payload))
+	for i, b := range payload {
+		out[i] = b ^ mask[i%4]
+	}
+	return out
+}
+
+func TestReadFrame(t *testing.T) {
+	mask := [4]byte{0x11, 0x22, 0x33, 0x44}
+
+	cases := []struct {
+		name    string
+		opcode  int
+		payload []byte
+		masked  bool
+	}{
+		{"short unmasked", opcodeBinary, bytes.Repeat([]byte{0xAB}, 10), false},
+		{"short masked", opcodeBinary, bytes.Repeat([]byte{0xAB}, 10), true},
+		{"extended16 masked", opcodeBinary, bytes.Repeat([]byte{0xCD}, 1000), true},
+		{"extended64 masked", opcodeBinary, bytes.Repeat([]byte{0xEF}, 70000), true},
+		{"empty masked", opcodeClose, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			b0 := byte(0x80) | byte(tc.opcode&0x0f)
+			length := len(tc.payload)
+			var b1 byte
+			if tc.masked {
+				b1 = 0x80
+			}
+			switch {
+			case length <= 125:
+				buf.Write([]byte{b0, b1 | byte(length)})
+			case length <= 65535:
+				buf.Write([]byte{b0, b1 | 126, byte(length >> 8), byte(length)})
+			default:
+				header := make([]byte, 10)
+				header[0] = b0
+				header[1] = b1 | 127
+				for i := 0; i < 8; i++ {
+					header[9-i] = byte(length >> (8 * i))
+				}
+				buf.Write(header)
+			}
+
+			wire := tc.payload
+			if tc.masked {
+				buf.Write(mask[:])
+				wire = maskPayload(tc.payload, mask)
+			}
+			buf.Write(wire)
+
+			opcode, payload, err := readFrame(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("readFrame: %v", err)
+			}
+			if opcode != tc.opcode {
+				t.Fatalf("opcode = %d, want %d", opcode, tc.opcode)
+			}
+			if !bytes.Equal(payload, tc.payload) {
+				t.Fatalf("payload mismatch: got %d bytes, want %d bytes", len(payload), len(tc.payload))
+			}
+		})
+	}
+}
+
+func TestWriteFrameThenReadFrame(t *testing.T) {
+	sizes := []int{0, 1, 125, 126, 65535, 65536}
+	for _, size := range sizes {
+		payload := bytes.Repeat([]byte{0x5A}, size)
+
+		var buf bytes.Buffer
+		if err := writeFrame(&buf, opcodeText, payload); err != nil {
+			t.Fatalf("writeFrame(%d): %v", size, err)
+		}
+
+		opcode, got, err := readFrame(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("readFrame(%d): %v", size, err)
+		}
+		if opcode != opcodeText {
+			t.Fatalf("opcode = %d, want %d", opcode, opcodeText)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("size %d: payload mismatch, got %d bytes, want %d bytes", size, len(got), len(payload))
+		}
+	}
+}
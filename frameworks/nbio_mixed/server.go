@@ -23,6 +23,7 @@ var (
 	payload           = flag.Int("b", 1024, `read buffer size`)
 	memLimit          = flag.Int64("m", 1024*1024*1024*2, `memory limit`)
 	maxBlockingOnline = flag.Int("mb", 10000, `max blocking online num, e.g. 10000`)
+	compression       = flag.Bool("compression", false, `enable permessage-deflate, see RFC 7692`)
 	_                 = flag.Int("mrb", 4096, `max read buffer size`)
 	_                 = flag.Bool("tpn", true, `benchmark: whether enable TPN caculation`)
 
@@ -41,6 +42,7 @@ func main() {
 	})
 	upgrader.KeepaliveTime = 0
 	upgrader.BlockingModAsyncWrite = false
+	upgrader.EnableCompression(*compression)
 
 	addrs, err := config.GetFrameworkServerAddrs(config.NbioModMixed)
 	if err != nil {
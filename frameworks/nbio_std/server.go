@@ -18,12 +18,13 @@ import (
 )
 
 var (
-	nodelay = flag.Bool("nodelay", true, `tcp nodelay`)
-	payload = flag.Int("b", 1024, `read buffer size`)
-	_       = flag.Int("mrb", 4096, `max read buffer size`)
-	_       = flag.Int64("m", 1024*1024*1024*2, `memory limit`)
-	_       = flag.Int("mb", 10000, `max blocking online num, e.g. 10000`)
-	_       = flag.Bool("tpn", true, `benchmark: whether enable TPN caculation`)
+	nodelay     = flag.Bool("nodelay", true, `tcp nodelay`)
+	payload     = flag.Int("b", 1024, `read buffer size`)
+	compression = flag.Bool("compression", false, `enable permessage-deflate, see RFC 7692`)
+	_           = flag.Int("mrb", 4096, `max read buffer size`)
+	_           = flag.Int64("m", 1024*1024*1024*2, `memory limit`)
+	_           = flag.Int("mb", 10000, `max blocking online num, e.g. 10000`)
+	_           = flag.Bool("tpn", true, `benchmark: whether enable TPN caculation`)
 
 	upgrader = websocket.NewUpgrader()
 )
@@ -37,6 +38,7 @@ func main() {
 	})
 	upgrader.KeepaliveTime = 0
 	upgrader.BlockingModAsyncWrite = false
+	upgrader.EnableCompression(*compression)
 
 	addrs, err := config.GetFrameworkServerAddrs(config.NbioStd)
 	if err != nil {
@@ -0,0 +1,14 @@
+// Package logging is the thin, shared logger used by mwsbench and the
+// frameworks/* servers so output is prefixed and timestamped consistently
+// across every binary in this repo.
+package logging
+
+import "log"
+
+func Printf(format string, args ...any) {
+	log.Printf(format, args...)
+}
+
+func Fatalf(format string, args ...any) {
+	log.Fatalf(format, args...)
+}
@@ -9,10 +9,13 @@ import (
 	"math/rand"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go-websocket-benchmark/config"
 	"go-websocket-benchmark/logging"
+	"go-websocket-benchmark/mwsbench/livestats"
+	"go-websocket-benchmark/mwsbench/profiling"
 	"go-websocket-benchmark/mwsbench/protocol"
 	"go-websocket-benchmark/mwsbench/report"
 
@@ -32,6 +35,43 @@ type BenchEcho struct {
 	Percents    []int
 	PsInterval  time.Duration
 
+	// Payloads, when non-empty, sweeps Run over each payload size in turn
+	// instead of running once at Payload, producing one report.Report per
+	// size. Each cell gets its own warmup and a Cooldown pause before the
+	// next, and reuses ConnsMap rather than reconnecting.
+	Payloads []int
+	// Cooldown is how long Run pauses between sweep cells so one cell's
+	// tail latency doesn't bleed into the next's warmup. Ignored with a
+	// single cell.
+	Cooldown time.Duration
+
+	// Compression enables RFC 7692 permessage-deflate for the request
+	// payload; CompressionLevel is the compress/flate level (0 = default),
+	// and requests smaller than CompressionThreshold bytes are sent
+	// uncompressed even when Compression is enabled, since deflating tiny
+	// payloads can cost more than it saves.
+	Compression          protocol.Compression
+	CompressionLevel     int
+	CompressionThreshold int
+
+	// PayloadEntropy controls how compressible the generated payload is,
+	// see protocol.GeneratePayload.
+	PayloadEntropy float64
+
+	// Profiling, when any field is set, captures a local client profile
+	// (and, with RemoteURL set, a matching server-side profile) for the
+	// measured phase only, skipping warmup.
+	Profiling profiling.Options
+
+	// LiveOutput redraws a terminal dashboard (progress, TPS, running
+	// latency mean/min/max/stddev) every LiveInterval during the measured
+	// phase instead of printing nothing until it completes.
+	LiveOutput   bool
+	LiveInterval time.Duration
+	// LiveJSON, when set, serves the same snapshots as NDJSON on this
+	// "host:port" so an external monitor can plot the run live.
+	LiveJSON string
+
 	// OutPreffix string
 	// OutSuffix  string
 
@@ -51,6 +91,10 @@ type BenchEcho struct {
 	limitFn func()
 
 	rbufferPool *sync.Pool
+
+	avgWireBytes int // mean on-wire payload size across wbuffers (frame headers excluded), for report.WireBytes
+
+	reports []report.Report
 }
 
 func New(framework string, benchmarkTimes int, ip string, connsMap map[*websocket.Conn]struct{}) *BenchEcho {
@@ -64,11 +108,32 @@ func New(framework string, benchmarkTimes int, ip string, connsMap map[*websocke
 	return bm
 }
 
+// Run measures once per payload size in Payloads (or once at Payload if
+// Payloads is empty), reusing ConnsMap across cells, and collects one
+// report.Report per cell for Reports. Each cell gets its own warmup; all
+// but the last are followed by a Cooldown pause so one cell's tail
+// latency doesn't bleed into the next's warmup.
 func (bm *BenchEcho) Run() {
 	bm.init()
 	defer bm.clean()
 
-	logging.Printf("BenchEcho Warmup for %d times ...", bm.WarmupTimes)
+	payloads := bm.Payloads
+	if len(payloads) == 0 {
+		payloads = []int{bm.Payload}
+	}
+
+	for i, payload := range payloads {
+		bm.runCell(payload)
+		if i < len(payloads)-1 && bm.Cooldown > 0 {
+			time.Sleep(bm.Cooldown)
+		}
+	}
+}
+
+func (bm *BenchEcho) runCell(payload int) {
+	bm.initCell(payload)
+
+	logging.Printf("BenchEcho Warmup for %d times (payload=%d) ...", bm.WarmupTimes, bm.Payload)
 	bm.Calculator.Warmup(bm.Concurrency, bm.WarmupTimes, bm.doOnce)
 	logging.Printf("BenchEcho Warmup for %d times done", bm.WarmupTimes)
 
@@ -86,24 +151,40 @@ func (bm *BenchEcho) Run() {
 		close(chCounterStart)
 	}()
 
-	logging.Printf("BenchEcho for %d times ...", bm.Total)
-	bm.Calculator.Benchmark(bm.Concurrency, bm.Total, bm.doOnce, bm.Percents)
+	profileSession := profiling.Start(bm.Profiling)
+
+	doOnce, stopLive := bm.withLiveOutput(bm.doOnce)
+
+	logging.Printf("BenchEcho for %d times (payload=%d) ...", bm.Total, bm.Payload)
+	bm.Calculator.Benchmark(bm.Concurrency, bm.Total, doOnce, bm.Percents)
 	logging.Printf("BenchEcho for %d times done", bm.Total)
 
+	stopLive()
+	profileSession.Stop()
+
 	<-chCounterStart
 	bm.PsCounter.Stop()
+
+	bm.reports = append(bm.reports, bm.reportCell())
 }
 
 func (bm *BenchEcho) Stop() {
 
 }
 
-func (bm *BenchEcho) Report() report.Report {
+// Reports returns one report.Report per payload cell run so far, in sweep
+// order.
+func (bm *BenchEcho) Reports() []report.Report {
+	return bm.reports
+}
+
+func (bm *BenchEcho) reportCell() report.Report {
 	return &report.BenchEchoReport{
 		Framework:   bm.Framework,
 		Connections: len(bm.ConnsMap),
 		Concurrency: bm.Concurrency,
 		Payload:     bm.Payload,
+		WireBytes:   bm.avgWireBytes,
 		Total:       bm.Total,
 		Success:     bm.Calculator.Success,
 		Failed:      bm.Calculator.Failed,
@@ -111,10 +192,10 @@ func (bm *BenchEcho) Report() report.Report {
 		CPUMin:      bm.PsCounter.CPUMin(),
 		CPUAvg:      bm.PsCounter.CPUAvg(),
 		CPUMax:      bm.PsCounter.CPUMax(),
-		MEMRSSMin:   bm.PsCounter.MEMRSSMin(),
-		MEMRSSAvg:   bm.PsCounter.MEMRSSAvg(),
-		MEMRSSMax:   bm.PsCounter.MEMRSSMax(),
-		TPS:         bm.Calculator.TPS(),
+		MEMRSSMin:   int64(bm.PsCounter.MEMRSSMin()),
+		MEMRSSAvg:   int64(bm.PsCounter.MEMRSSAvg()),
+		MEMRSSMax:   int64(bm.PsCounter.MEMRSSMax()),
+		TPS:         float64(bm.Calculator.TPS()),
 		Min:         bm.Calculator.Min,
 		Avg:         bm.Calculator.Avg,
 		Max:         bm.Calculator.Max,
@@ -126,6 +207,8 @@ func (bm *BenchEcho) Report() report.Report {
 	}
 }
 
+// init sets up everything that's shared across sweep cells: connection
+// defaults, the shared chConns pool, and the server pid.
 func (bm *BenchEcho) init() {
 	if bm.WarmupTimes <= 0 {
 		bm.WarmupTimes = len(bm.ConnsMap) * 5
@@ -142,18 +225,15 @@ func (bm *BenchEcho) init() {
 	if bm.Payload <= 0 {
 		bm.Payload = 1024
 	}
-	bm.rbufferPool = &sync.Pool{
-		New: func() any {
-			buf := make([]byte, bm.Payload)
-			return &buf
-		},
-	}
 	if bm.PsInterval <= 0 {
 		bm.PsInterval = time.Second
 	}
 	if len(bm.Percents) == 0 {
 		bm.Percents = []int{50, 75, 90, 95, 99}
 	}
+	if bm.Compression == "" {
+		bm.Compression = protocol.CompressionOff
+	}
 
 	if bm.Limit > 0 {
 		limiter := rate.NewLimiter(rate.Every(1*time.Second), bm.Limit)
@@ -162,15 +242,6 @@ func (bm *BenchEcho) init() {
 		}
 	}
 
-	bm.pbuffers = make([][]byte, 1024)
-	bm.wbuffers = make([][]byte, 1024)
-	for i := 0; i < len(bm.pbuffers); i++ {
-		buffer := make([]byte, bm.Payload)
-		rand.Read(buffer)
-		bm.pbuffers[i] = buffer
-		bm.wbuffers[i] = protocol.EncodeClientMessage(websocket.BinaryMessage, buffer)
-	}
-
 	bm.chConns = make(chan *websocket.Conn, len(bm.ConnsMap))
 	for c := range bm.ConnsMap {
 		bm.chConns <- c
@@ -181,13 +252,45 @@ func (bm *BenchEcho) init() {
 		logging.Fatalf("BenchEcho GetFrameworkPid(%v) failed: %v", bm.Framework, err)
 	}
 	bm.ServerPid = serverPid
-	psCounter, err := perf.NewPSCounter(serverPid)
+}
+
+// initCell (re)builds everything that depends on the cell's payload size:
+// the buffer pool, the request/response buffers, and a fresh Calculator
+// and PsCounter so one cell's numbers don't leak into the next's.
+func (bm *BenchEcho) initCell(payload int) {
+	bm.Payload = payload
+
+	bm.rbufferPool = &sync.Pool{
+		New: func() any {
+			buf := make([]byte, bm.Payload)
+			return &buf
+		},
+	}
+
+	bm.pbuffers = make([][]byte, 1024)
+	bm.wbuffers = make([][]byte, 1024)
+	wireBytesTotal := 0
+	for i := 0; i < len(bm.pbuffers); i++ {
+		buffer := protocol.GeneratePayload(bm.Payload, bm.PayloadEntropy)
+		bm.pbuffers[i] = buffer
+		if bm.Compression.Enabled() && bm.Payload >= bm.CompressionThreshold {
+			frame, wireLen := protocol.EncodeClientMessageDeflate(websocket.BinaryMessage, buffer, bm.CompressionLevel)
+			bm.wbuffers[i] = frame
+			wireBytesTotal += wireLen
+		} else {
+			bm.wbuffers[i] = protocol.EncodeClientMessage(websocket.BinaryMessage, buffer)
+			wireBytesTotal += bm.Payload
+		}
+	}
+	bm.avgWireBytes = wireBytesTotal / len(bm.wbuffers)
+
+	psCounter, err := perf.NewPSCounter(bm.ServerPid)
 	if err != nil {
 		panic(err)
 	}
 	bm.PsCounter = psCounter
 
-	bm.Calculator = perf.NewCalculator(fmt.Sprintf("%v-TPS", bm.Framework))
+	bm.Calculator = perf.NewCalculator(fmt.Sprintf("%v-TPS-%d", bm.Framework, bm.Payload))
 }
 
 func (bm *BenchEcho) clean() {
@@ -197,6 +300,60 @@ func (bm *BenchEcho) clean() {
 	bm.limitFn = func() {}
 }
 
+// withLiveOutput, if LiveOutput or LiveJSON is set, wraps fn to feed a
+// running LatencyStats and redraws a dashboard and/or streams NDJSON
+// snapshots every LiveInterval; otherwise it returns fn unchanged. The
+// returned stop func must be called after the measured phase finishes.
+func (bm *BenchEcho) withLiveOutput(fn func() error) (wrapped func() error, stop func()) {
+	if !bm.LiveOutput && bm.LiveJSON == "" {
+		return fn, func() {}
+	}
+
+	stats := livestats.NewLatencyStats()
+	var done int64
+
+	var stream *livestats.JSONStream
+	if bm.LiveJSON != "" {
+		stream = livestats.NewJSONStream()
+		stream.ListenAndServe(bm.LiveJSON)
+	}
+
+	render := func() string {
+		snap := stats.Snapshot()
+		if stream != nil {
+			stream.Publish(snap)
+		}
+		return fmt.Sprintf("%s  tps(inst)=%.0f tps(cum)=%.0f mean=%v min=%v max=%v stddev=%v",
+			livestats.ProgressBar(atomic.LoadInt64(&done), int64(bm.Total), 30),
+			snap.TPSInstant, snap.TPSCumulative, snap.Mean, snap.Min, snap.Max, snap.StdDev)
+	}
+
+	var dashboard *livestats.Dashboard
+	var ticker *livestats.Ticker
+	if bm.LiveOutput {
+		dashboard = livestats.StartDashboard(bm.LiveInterval, render)
+	} else {
+		ticker = livestats.StartTicker(bm.LiveInterval, func() { render() })
+	}
+
+	wrapped = func() error {
+		start := time.Now()
+		err := fn()
+		stats.Record(time.Since(start))
+		atomic.AddInt64(&done, 1)
+		return err
+	}
+	stop = func() {
+		if dashboard != nil {
+			dashboard.Stop(render)
+		}
+		if ticker != nil {
+			ticker.Stop(func() { render() })
+		}
+	}
+	return wrapped, stop
+}
+
 func (bm *BenchEcho) getBuffers() ([]byte, []byte) {
 	idx := uint32(rand.Intn(len(bm.wbuffers))) % uint32(len(bm.wbuffers))
 	return bm.pbuffers[idx], bm.wbuffers[idx]
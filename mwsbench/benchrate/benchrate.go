@@ -3,7 +3,7 @@ package benchrate
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
+	"fmt"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -11,6 +11,8 @@ import (
 
 	"go-websocket-benchmark/config"
 	"go-websocket-benchmark/logging"
+	"go-websocket-benchmark/mwsbench/livestats"
+	"go-websocket-benchmark/mwsbench/profiling"
 	"go-websocket-benchmark/mwsbench/protocol"
 	"go-websocket-benchmark/mwsbench/report"
 
@@ -29,14 +31,53 @@ type BenchRate struct {
 	SendLimit   int
 	PsInterval  time.Duration
 
+	// Payloads and SendRates, when non-empty, sweep Run over their
+	// cross-product instead of running once at Payload/SendRate,
+	// producing one report.Report per cell. Each cell reuses ConnsMap and
+	// is followed by a Cooldown pause before the next.
+	Payloads  []int
+	SendRates []int
+	Cooldown  time.Duration
+
+	// Compression enables RFC 7692 permessage-deflate for the send
+	// payload; CompressionLevel is the compress/flate level (0 = default),
+	// and payloads smaller than CompressionThreshold bytes are sent
+	// uncompressed even when Compression is enabled.
+	Compression          protocol.Compression
+	CompressionLevel     int
+	CompressionThreshold int
+
+	// PayloadEntropy controls how compressible the generated payload is,
+	// see protocol.GeneratePayload.
+	PayloadEntropy float64
+
+	// Profiling, when any field is set, captures a local client profile
+	// (and, with RemoteURL set, a matching server-side profile) for the
+	// run's Duration.
+	Profiling profiling.Options
+
+	// LiveOutput redraws a terminal dashboard (send/recv MB/s, inflight)
+	// every LiveInterval instead of printing nothing until Duration ends.
+	LiveOutput   bool
+	LiveInterval time.Duration
+	// LiveJSON, when set, serves the same snapshots as NDJSON on this
+	// "host:port" so an external monitor can plot the run live.
+	LiveJSON string
+
 	ServerPid int
 	PsCounter *perf.PSCounter
 
 	ConnsMap map[*websocket.Conn]struct{}
 
-	wbuffer []byte
+	// cell holds the current sweep cell's *cellState. onMessage runs on
+	// nbio's I/O goroutines and may still be comparing echoes against the
+	// previous cell's payload when initCell starts building the next one,
+	// so this is swapped atomically rather than storing wbuffer/wireRatio
+	// as plain fields.
+	cell atomic.Value
 
-	chConns chan *websocket.Conn
+	chConns   chan *websocket.Conn
+	connTeams [][]*Conn
 
 	limitFn func()
 
@@ -44,10 +85,25 @@ type BenchRate struct {
 	batchBuffer []byte
 	tickRate    int
 
+	reports []report.Report
+
 	sendTimes int64
 	sendBytes int64
 	recvTimes int64
 	recvBytes int64
+
+	wireSendBytes int64
+	wireRecvBytes int64
+}
+
+// cellState is the sweep-cell data onMessage needs to recognize and
+// account for an echo: the exact bytes a cell sent, and the wire/logical
+// ratio used to estimate received wire bytes (received messages arrive
+// already decompressed, and the library doesn't expose their on-wire
+// size).
+type cellState struct {
+	wbuffer   []byte
+	wireRatio float64
 }
 
 type Conn struct {
@@ -66,10 +122,37 @@ func New(framework string, ip string, connsMap map[*websocket.Conn]struct{}) *Be
 	return bm
 }
 
+// Run measures once per (payload, sendRate) cell in the cross-product of
+// Payloads and SendRates (or once at Payload/SendRate if both are empty),
+// reusing ConnsMap across cells. All but the last cell are followed by a
+// Cooldown pause so one cell's backlog doesn't bleed into the next.
 func (br *BenchRate) Run() {
 	br.init()
 	defer br.clean()
 
+	payloads := br.Payloads
+	if len(payloads) == 0 {
+		payloads = []int{br.Payload}
+	}
+	sendRates := br.SendRates
+	if len(sendRates) == 0 {
+		sendRates = []int{br.SendRate}
+	}
+
+	for pi, payload := range payloads {
+		for si, sendRate := range sendRates {
+			br.runCell(payload, sendRate)
+			last := pi == len(payloads)-1 && si == len(sendRates)-1
+			if !last && br.Cooldown > 0 {
+				time.Sleep(br.Cooldown)
+			}
+		}
+	}
+}
+
+func (br *BenchRate) runCell(payload, sendRate int) {
+	connTeams := br.initCell(payload, sendRate)
+
 	chCounterStart := make(chan struct{})
 	go func() {
 		br.PsCounter.Start(perf.PSCountOptions{
@@ -88,21 +171,15 @@ func (br *BenchRate) Run() {
 		close(done)
 	})
 
-	logging.Printf("BenchRate for %.2f seconds ...", br.Duration.Seconds())
+	profileSession := profiling.Start(br.Profiling)
+	defer profileSession.Stop()
 
-	wg := sync.WaitGroup{}
+	stopLive := br.startLiveOutput()
+	defer stopLive()
 
-	connTeams := make([][]*Conn, br.Concurrency)
-	cnt := 0
-	for wsc := range br.ConnsMap {
-		cnt++
-		idx := cnt % len(connTeams)
-		conn := &Conn{
-			Conn: wsc.Conn,
-		}
-		connTeams[idx] = append(connTeams[idx], conn)
-		wsc.SetSession(conn)
-	}
+	logging.Printf("BenchRate for %.2f seconds (payload=%d, sendRate=%d) ...", br.Duration.Seconds(), br.Payload, br.SendRate)
+
+	wg := sync.WaitGroup{}
 	for i := 0; i < br.Concurrency; i++ {
 		wg.Add(1)
 		conns := connTeams[i]
@@ -126,32 +203,44 @@ func (br *BenchRate) Run() {
 
 	<-chCounterStart
 	br.PsCounter.Stop()
+
+	br.reports = append(br.reports, br.reportCell())
 }
 
 func (br *BenchRate) Stop() {
 
 }
 
-func (br *BenchRate) Report() report.Report {
+// Reports returns one report.Report per (payload, sendRate) cell run so
+// far, in sweep order.
+func (br *BenchRate) Reports() []report.Report {
+	return br.reports
+}
+
+func (br *BenchRate) reportCell() report.Report {
 	return &report.BenchRateReport{
-		Framework:   br.Framework,
-		Duration:    br.Duration.Nanoseconds(),
-		Connections: len(br.ConnsMap),
-		SendRate:    br.SendRate,
-		Payload:     br.Payload,
-		SendTimes:   br.sendTimes,
-		SendBytes:   br.sendBytes,
-		RecvTimes:   br.recvTimes,
-		RecvBytes:   br.recvBytes,
-		CPUMin:      br.PsCounter.CPUMin(),
-		CPUAvg:      br.PsCounter.CPUAvg(),
-		CPUMax:      br.PsCounter.CPUMax(),
-		MEMRSSMin:   br.PsCounter.MEMRSSMin(),
-		MEMRSSAvg:   br.PsCounter.MEMRSSAvg(),
-		MEMRSSMax:   br.PsCounter.MEMRSSMax(),
+		Framework:     br.Framework,
+		Duration:      br.Duration.Nanoseconds(),
+		Connections:   len(br.ConnsMap),
+		SendRate:      br.SendRate,
+		Payload:       br.Payload,
+		SendTimes:     br.sendTimes,
+		SendBytes:     br.sendBytes,
+		WireSendBytes: br.wireSendBytes,
+		RecvTimes:     br.recvTimes,
+		RecvBytes:     br.recvBytes,
+		WireRecvBytes: br.wireRecvBytes,
+		CPUMin:        br.PsCounter.CPUMin(),
+		CPUAvg:        br.PsCounter.CPUAvg(),
+		CPUMax:        br.PsCounter.CPUMax(),
+		MEMRSSMin:     int64(br.PsCounter.MEMRSSMin()),
+		MEMRSSAvg:     int64(br.PsCounter.MEMRSSAvg()),
+		MEMRSSMax:     int64(br.PsCounter.MEMRSSMax()),
 	}
 }
 
+// init sets up everything that's shared across sweep cells: duration,
+// concurrency, connection teams, and the server pid.
 func (br *BenchRate) init() {
 	if br.Duration <= 0 {
 		br.Duration = time.Second * 10
@@ -162,33 +251,85 @@ func (br *BenchRate) init() {
 	if br.Concurrency > len(br.ConnsMap) {
 		br.Concurrency = len(br.ConnsMap)
 	}
+	if br.Payload <= 0 {
+		br.Payload = 1024
+	}
 	if br.SendRate <= 0 {
 		br.SendRate = 1
 	}
-	if br.Payload <= 0 {
-		br.Payload = 1024
+	if br.PsInterval <= 0 {
+		br.PsInterval = time.Second
+	}
+	if br.Compression == "" {
+		br.Compression = protocol.CompressionOff
+	}
+	br.Profiling.Duration = br.Duration
+
+	br.connTeams = make([][]*Conn, br.Concurrency)
+	cnt := 0
+	for wsc := range br.ConnsMap {
+		cnt++
+		idx := cnt % len(br.connTeams)
+		conn := &Conn{
+			Conn: wsc.Conn,
+		}
+		br.connTeams[idx] = append(br.connTeams[idx], conn)
+		wsc.SetSession(conn)
 	}
 
-	br.wbuffer = make([]byte, br.Payload)
-	rand.Read(br.wbuffer)
-	message := protocol.EncodeClientMessage(websocket.BinaryMessage, br.wbuffer)
+	serverPid, err := config.GetFrameworkPid(br.Framework, br.Ip)
+	if err != nil {
+		logging.Fatalf("BenchRate GetFrameworkPid(%v) failed: %v", br.Framework, err)
+	}
+	br.ServerPid = serverPid
+}
+
+// initCell (re)builds everything that depends on the cell's payload size
+// and send rate: the wire buffers, the read/write counters, and a fresh
+// PsCounter so one cell's numbers don't leak into the next's. It returns
+// the shared connection teams with their per-connection counters reset.
+func (br *BenchRate) initCell(payload, sendRate int) [][]*Conn {
+	br.Payload = payload
+	br.SendRate = sendRate
+
+	wbuffer := protocol.GeneratePayload(br.Payload, br.PayloadEntropy)
+
+	var message []byte
+	cell := &cellState{wbuffer: wbuffer}
+	if br.Compression.Enabled() && br.Payload >= br.CompressionThreshold {
+		var wireLen int
+		message, wireLen = protocol.EncodeClientMessageDeflate(int(websocket.BinaryMessage), wbuffer, br.CompressionLevel)
+		cell.wireRatio = float64(wireLen) / float64(br.Payload)
+	} else {
+		message = protocol.EncodeClientMessage(int(websocket.BinaryMessage), wbuffer)
+		cell.wireRatio = 1
+	}
+	// Published only once the cell is fully built, and not read back by
+	// this goroutine, so any echo onMessage matches against is either this
+	// cell's own wbuffer or a still-valid previous one — never a half
+	// constructed one.
+	br.cell.Store(cell)
 	br.batchBuffer, br.batch, br.tickRate = protocol.BatchBuffers(message, br.SendRate, 1024*8)
-	// br.batchBuffer, br.batch, br.tickRate = message, 1, br.SendRate
 	if br.tickRate <= 0 || len(br.batchBuffer) == 0 {
 		logging.Fatalf("BenchRate get wrong tickRate: %v, or batchBuffer: %v", br.tickRate, len(br.batchBuffer))
 	}
 
-	if br.PsInterval <= 0 {
-		br.PsInterval = time.Second
-	}
-
 	if br.SendLimit > 0 {
 		limiter := rate.NewLimiter(rate.Every(1*time.Second), br.SendLimit)
 		br.limitFn = func() {
 			limiter.WaitN(context.Background(), len(br.batchBuffer)/br.Payload)
 		}
+	} else {
+		br.limitFn = func() {}
 	}
 
+	atomic.StoreInt64(&br.sendTimes, 0)
+	atomic.StoreInt64(&br.sendBytes, 0)
+	atomic.StoreInt64(&br.recvTimes, 0)
+	atomic.StoreInt64(&br.recvBytes, 0)
+	atomic.StoreInt64(&br.wireSendBytes, 0)
+	atomic.StoreInt64(&br.wireRecvBytes, 0)
+
 	br.chConns = make(chan *websocket.Conn, len(br.ConnsMap)*br.SendRate)
 	for c := range br.ConnsMap {
 		c.OnMessage(br.onMessage)
@@ -199,25 +340,63 @@ func (br *BenchRate) init() {
 		}
 	}
 
-	serverPid, err := config.GetFrameworkPid(br.Framework, br.Ip)
-	if err != nil {
-		logging.Fatalf("BenchRate GetFrameworkPid(%v) failed: %v", br.Framework, err)
+	for _, conns := range br.connTeams {
+		for _, conn := range conns {
+			atomic.StoreInt64(&conn.sendCnt, 0)
+			atomic.StoreInt64(&conn.recvCnt, 0)
+		}
 	}
-	br.ServerPid = serverPid
-	psCounter, err := perf.NewPSCounter(serverPid)
+
+	psCounter, err := perf.NewPSCounter(br.ServerPid)
 	if err != nil {
 		panic(err)
 	}
 	br.PsCounter = psCounter
+
+	return br.connTeams
 }
 
 func (br *BenchRate) clean() {
 	br.chConns = nil
+	br.connTeams = nil
 	br.limitFn = func() {}
 }
 
-func (br *BenchRate) getWriteBuffer() []byte {
-	return br.wbuffer
+// startLiveOutput redraws a terminal dashboard and/or streams NDJSON
+// snapshots of send/recv MB/s and inflight count every LiveInterval. The
+// returned stop func must be called once the run finishes.
+func (br *BenchRate) startLiveOutput() (stop func()) {
+	if !br.LiveOutput && br.LiveJSON == "" {
+		return func() {}
+	}
+
+	tracker := livestats.NewRateTracker(func() (sendTimes, sendBytes, recvTimes, recvBytes int64) {
+		return atomic.LoadInt64(&br.sendTimes), atomic.LoadInt64(&br.sendBytes),
+			atomic.LoadInt64(&br.recvTimes), atomic.LoadInt64(&br.recvBytes)
+	})
+
+	var stream *livestats.JSONStream
+	if br.LiveJSON != "" {
+		stream = livestats.NewJSONStream()
+		stream.ListenAndServe(br.LiveJSON)
+	}
+
+	render := func() string {
+		snap := tracker.Snapshot()
+		if stream != nil {
+			stream.Publish(snap)
+		}
+		return fmt.Sprintf("send=%.2fMB/s recv=%.2fMB/s inflight=%d sent=%d recv=%d",
+			snap.SendMBPerSec, snap.RecvMBPerSec, snap.Inflight, snap.SendTimes, snap.RecvTimes)
+	}
+
+	if br.LiveOutput {
+		dashboard := livestats.StartDashboard(br.LiveInterval, render)
+		return func() { dashboard.Stop(render) }
+	}
+
+	ticker := livestats.StartTicker(br.LiveInterval, func() { render() })
+	return func() { ticker.Stop(func() { render() }) }
 }
 
 func (br *BenchRate) doOnce(conns []*Conn) {
@@ -228,6 +407,7 @@ func (br *BenchRate) doOnce(conns []*Conn) {
 			if err == nil {
 				atomic.AddInt64(&br.sendTimes, int64(br.batch))
 				atomic.AddInt64(&br.sendBytes, int64(br.batch*br.Payload))
+				atomic.AddInt64(&br.wireSendBytes, int64(float64(br.batch*br.Payload)*br.cell.Load().(*cellState).wireRatio))
 				atomic.AddInt64(&conn.sendCnt, int64(br.batch))
 			}
 		}
@@ -235,10 +415,12 @@ func (br *BenchRate) doOnce(conns []*Conn) {
 }
 
 func (br *BenchRate) onMessage(c *websocket.Conn, mt websocket.MessageType, b []byte) {
-	if mt == websocket.BinaryMessage && bytes.Equal(b, br.getWriteBuffer()) {
+	cell := br.cell.Load().(*cellState)
+	if mt == websocket.BinaryMessage && bytes.Equal(b, cell.wbuffer) {
 		conn := c.Session().(*Conn)
 		atomic.AddInt64(&conn.recvCnt, 1)
 		atomic.AddInt64(&br.recvTimes, 1)
 		atomic.AddInt64(&br.recvBytes, int64(len(b)))
+		atomic.AddInt64(&br.wireRecvBytes, int64(float64(len(b))*cell.wireRatio))
 	}
 }
@@ -0,0 +1,152 @@
+// Command benchresult pretty-prints a single mwsbench result file, or
+// diffs two of them and flags regressions, mirroring the workflow of
+// gRPC's benchmain/benchresult: save a "base" file on main, then diff your
+// branch's numbers against it without hand-editing markdown tables.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-websocket-benchmark/logging"
+	"go-websocket-benchmark/mwsbench/report"
+)
+
+var threshold = flag.Float64("threshold", 5.0, "percent change considered a regression")
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+
+	switch len(args) {
+	case 1:
+		printFile(args[0])
+	case 2:
+		diffFiles(args[0], args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "usage: benchresult <result file> | benchresult <base result file> <new result file>")
+		os.Exit(2)
+	}
+}
+
+func printFile(path string) {
+	rf, err := report.LoadResultFile(path)
+	if err != nil {
+		logging.Fatalf("benchresult: %v", err)
+	}
+	for i := range rf.Echo {
+		printTable(&rf.Echo[i])
+	}
+	for i := range rf.Rate {
+		printTable(&rf.Rate[i])
+	}
+}
+
+func printTable(r report.Report) {
+	fmt.Println("| " + strings.Join(r.Headers(), " | ") + " |")
+	fmt.Println("| " + strings.Repeat("--- | ", len(r.Headers())))
+	fmt.Println("| " + strings.Join(r.Values(), " | ") + " |")
+}
+
+func diffFiles(basePath, nextPath string) {
+	base, err := report.LoadResultFile(basePath)
+	if err != nil {
+		logging.Fatalf("benchresult: %v", err)
+	}
+	next, err := report.LoadResultFile(nextPath)
+	if err != nil {
+		logging.Fatalf("benchresult: %v", err)
+	}
+
+	regressed := false
+	regressed = diffEcho(base.Echo, next.Echo) || regressed
+	regressed = diffRate(base.Rate, next.Rate) || regressed
+
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+func diffEcho(base, next []report.BenchEchoReport) bool {
+	regressed := false
+	for _, n := range next {
+		b := findEcho(base, n.Framework, n.Payload)
+		if b == nil {
+			continue
+		}
+		fmt.Printf("## %s (payload=%d)\n", n.Framework, n.Payload)
+		regressed = reportDelta("TPS", b.TPS, n.TPS, true) || regressed
+		regressed = reportDelta("TP50", float64(b.TP50), float64(n.TP50), false) || regressed
+		regressed = reportDelta("TP90", float64(b.TP90), float64(n.TP90), false) || regressed
+		regressed = reportDelta("TP95", float64(b.TP95), float64(n.TP95), false) || regressed
+		regressed = reportDelta("TP99", float64(b.TP99), float64(n.TP99), false) || regressed
+		regressed = reportDelta("CPU Avg", b.CPUAvg, n.CPUAvg, false) || regressed
+		regressed = reportDelta("CPU Max", b.CPUMax, n.CPUMax, false) || regressed
+		regressed = reportDelta("RSS Avg", float64(b.MEMRSSAvg), float64(n.MEMRSSAvg), false) || regressed
+		regressed = reportDelta("RSS Max", float64(b.MEMRSSMax), float64(n.MEMRSSMax), false) || regressed
+	}
+	return regressed
+}
+
+func diffRate(base, next []report.BenchRateReport) bool {
+	regressed := false
+	for _, n := range next {
+		b := findRate(base, n.Framework, n.Payload, n.SendRate)
+		if b == nil {
+			continue
+		}
+		fmt.Printf("## %s (payload=%d, sendRate=%d)\n", n.Framework, n.Payload, n.SendRate)
+		regressed = reportDelta("SendBytes", float64(b.SendBytes), float64(n.SendBytes), true) || regressed
+		regressed = reportDelta("RecvBytes", float64(b.RecvBytes), float64(n.RecvBytes), true) || regressed
+		regressed = reportDelta("WireSendBytes", float64(b.WireSendBytes), float64(n.WireSendBytes), true) || regressed
+		regressed = reportDelta("WireRecvBytes", float64(b.WireRecvBytes), float64(n.WireRecvBytes), true) || regressed
+		regressed = reportDelta("CPU Avg", b.CPUAvg, n.CPUAvg, false) || regressed
+		regressed = reportDelta("CPU Max", b.CPUMax, n.CPUMax, false) || regressed
+		regressed = reportDelta("RSS Avg", float64(b.MEMRSSAvg), float64(n.MEMRSSAvg), false) || regressed
+		regressed = reportDelta("RSS Max", float64(b.MEMRSSMax), float64(n.MEMRSSMax), false) || regressed
+	}
+	return regressed
+}
+
+// findEcho locates the base row matching a next row's (framework, payload)
+// cell, since a single result file can now hold one row per sweep cell.
+func findEcho(reports []report.BenchEchoReport, framework string, payload int) *report.BenchEchoReport {
+	for i := range reports {
+		if reports[i].Framework == framework && reports[i].Payload == payload {
+			return &reports[i]
+		}
+	}
+	return nil
+}
+
+// findRate locates the base row matching a next row's (framework, payload,
+// sendRate) cell, since a single result file can now hold one row per
+// sweep cell.
+func findRate(reports []report.BenchRateReport, framework string, payload, sendRate int) *report.BenchRateReport {
+	for i := range reports {
+		if reports[i].Framework == framework && reports[i].Payload == payload && reports[i].SendRate == sendRate {
+			return &reports[i]
+		}
+	}
+	return nil
+}
+
+// reportDelta prints a "name  base -> next (+pct%)" line and reports
+// whether that change is a regression. higherIsBetter controls the sign a
+// drop beyond -threshold% is treated as a regression versus a rise beyond
+// +threshold% (e.g. TPS dropping is bad, latency/CPU/RSS rising is bad).
+func reportDelta(name string, base, next float64, higherIsBetter bool) bool {
+	pct := 0.0
+	if base != 0 {
+		pct = (next - base) / base * 100
+	}
+	flag := ""
+	isRegression := (higherIsBetter && pct < -*threshold) || (!higherIsBetter && pct > *threshold)
+	if isRegression {
+		flag = " REGRESSION"
+	}
+	fmt.Printf("  %-10s %12.2f -> %12.2f (%+.2f%%)%s\n", name, base, next, pct, flag)
+	return isRegression
+}
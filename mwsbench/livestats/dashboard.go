@@ -0,0 +1,107 @@
+package livestats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gosuri/uilive"
+)
+
+// Dashboard redraws render's output to the terminal every interval until
+// Stop is called, in the style of p0d's live progress output.
+type Dashboard struct {
+	writer *uilive.Writer
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+// StartDashboard begins redrawing render()'s output every interval. render
+// is called from the dashboard's own goroutine, so it must be safe to call
+// concurrently with whatever updates the stats it reads.
+func StartDashboard(interval time.Duration, render func() string) *Dashboard {
+	if interval <= 0 {
+		interval = 200 * time.Millisecond
+	}
+
+	w := uilive.New()
+	w.Start()
+
+	d := &Dashboard{
+		writer: w,
+		ticker: time.NewTicker(interval),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		for {
+			select {
+			case <-d.ticker.C:
+				fmt.Fprintln(d.writer, render())
+			case <-d.done:
+				return
+			}
+		}
+	}()
+
+	return d
+}
+
+// Stop redraws once more with the final state and stops the dashboard.
+func (d *Dashboard) Stop(render func() string) {
+	close(d.done)
+	d.ticker.Stop()
+	fmt.Fprintln(d.writer, render())
+	d.writer.Stop()
+}
+
+// Ticker calls tick every interval until Stop, without drawing anything to
+// the terminal. It's used to drive -liveJSON publishing when the terminal
+// dashboard itself is disabled.
+type Ticker struct {
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+func StartTicker(interval time.Duration, tick func()) *Ticker {
+	if interval <= 0 {
+		interval = 200 * time.Millisecond
+	}
+	t := &Ticker{ticker: time.NewTicker(interval), done: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				tick()
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func (t *Ticker) Stop(final func()) {
+	close(t.done)
+	t.ticker.Stop()
+	final()
+}
+
+// ProgressBar renders a "[#####-----] done/total" bar width characters wide.
+func ProgressBar(done, total int64, width int) string {
+	if total <= 0 {
+		total = 1
+	}
+	filled := int(float64(width) * float64(done) / float64(total))
+	if filled > width {
+		filled = width
+	}
+	bar := make([]byte, width)
+	for i := range bar {
+		if i < filled {
+			bar[i] = '#'
+		} else {
+			bar[i] = '-'
+		}
+	}
+	return fmt.Sprintf("[%s] %d/%d", bar, done, total)
+}
@@ -0,0 +1,82 @@
+package livestats
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"go-websocket-benchmark/logging"
+)
+
+// JSONStream serves newline-delimited JSON snapshots over HTTP so an
+// external monitor can plot a run live, as an alternative to (or alongside)
+// the terminal Dashboard.
+type JSONStream struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+func NewJSONStream() *JSONStream {
+	return &JSONStream{clients: map[chan []byte]struct{}{}}
+}
+
+// Publish marshals v as one NDJSON line and fans it out to every connected
+// client, dropping the line for any client that isn't keeping up.
+func (s *JSONStream) Publish(v any) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		logging.Printf("livestats: marshal snapshot: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (s *JSONStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 16)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for {
+		select {
+		case line := <-ch:
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ListenAndServe starts an HTTP server on addr whose only route streams
+// NDJSON snapshots published via Publish.
+func (s *JSONStream) ListenAndServe(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, s); err != nil {
+			logging.Printf("livestats: -liveJSON server on %v stopped: %v", addr, err)
+		}
+	}()
+}
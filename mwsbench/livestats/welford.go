@@ -0,0 +1,162 @@
+// Package livestats tracks running statistics during a BenchEcho/BenchRate
+// run and redraws a terminal dashboard (and/or streams NDJSON snapshots)
+// while the run is still in progress, instead of printing nothing until it
+// completes.
+package livestats
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LatencyStats accumulates per-request latency using Welford's online
+// algorithm, so mean/variance can be updated one sample at a time without
+// keeping every sample around:
+//
+//	mean_n = mean_{n-1} + (x-mean_{n-1})/n
+//	M2_n   = M2_{n-1} + (x-mean_{n-1})*(x-mean_n)
+//	variance = M2/(n-1)
+type LatencyStats struct {
+	mu    sync.Mutex
+	count int64
+	mean  float64
+	m2    float64
+	min   time.Duration
+	max   time.Duration
+
+	windowCount int64
+	windowStart time.Time
+	started     time.Time
+}
+
+func NewLatencyStats() *LatencyStats {
+	now := time.Now()
+	return &LatencyStats{windowStart: now, started: now}
+}
+
+// Record folds one more latency sample into the running statistics.
+func (s *LatencyStats) Record(d time.Duration) {
+	x := float64(d)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+
+	if s.count == 1 || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.windowCount++
+}
+
+// LatencySnapshot is a point-in-time read of LatencyStats, plus the
+// instantaneous and cumulative TPS derived from elapsed wall time.
+type LatencySnapshot struct {
+	Count         int64         `json:"count"`
+	Mean          time.Duration `json:"meanNs"`
+	Min           time.Duration `json:"minNs"`
+	Max           time.Duration `json:"maxNs"`
+	StdDev        time.Duration `json:"stdDevNs"`
+	TPSInstant    float64       `json:"tpsInstant"`
+	TPSCumulative float64       `json:"tpsCumulative"`
+}
+
+// Snapshot reads the current statistics and resets the instantaneous
+// window (used for TPSInstant) to start again from now.
+func (s *LatencyStats) Snapshot() LatencySnapshot {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	variance := 0.0
+	if s.count > 1 {
+		variance = s.m2 / float64(s.count-1)
+	}
+
+	windowElapsed := now.Sub(s.windowStart).Seconds()
+	tpsInstant := 0.0
+	if windowElapsed > 0 {
+		tpsInstant = float64(s.windowCount) / windowElapsed
+	}
+	totalElapsed := now.Sub(s.started).Seconds()
+	tpsCumulative := 0.0
+	if totalElapsed > 0 {
+		tpsCumulative = float64(s.count) / totalElapsed
+	}
+
+	snap := LatencySnapshot{
+		Count:         s.count,
+		Mean:          time.Duration(s.mean),
+		Min:           s.min,
+		Max:           s.max,
+		StdDev:        time.Duration(math.Sqrt(variance)),
+		TPSInstant:    tpsInstant,
+		TPSCumulative: tpsCumulative,
+	}
+
+	s.windowCount = 0
+	s.windowStart = now
+
+	return snap
+}
+
+// RateCounters reads BenchRate's cumulative send/recv counters. BenchRate
+// already maintains these as atomics for its final report, so RateTracker
+// reads them rather than duplicating the bookkeeping.
+type RateCounters func() (sendTimes, sendBytes, recvTimes, recvBytes int64)
+
+// RateTracker derives live send/recv MB/s and inflight (sent-received) from
+// a BenchRate run's cumulative counters, for the -mode=rate live dashboard.
+type RateTracker struct {
+	read RateCounters
+
+	lastSnapshot time.Time
+	lastSend     int64
+	lastRecv     int64
+}
+
+func NewRateTracker(read RateCounters) *RateTracker {
+	return &RateTracker{read: read, lastSnapshot: time.Now()}
+}
+
+type RateSnapshot struct {
+	SendMBPerSec float64 `json:"sendMBPerSec"`
+	RecvMBPerSec float64 `json:"recvMBPerSec"`
+	Inflight     int64   `json:"inflight"`
+	SendTimes    int64   `json:"sendTimes"`
+	RecvTimes    int64   `json:"recvTimes"`
+}
+
+// Snapshot reads the cumulative counters and returns the MB/s seen since
+// the previous Snapshot call.
+func (t *RateTracker) Snapshot() RateSnapshot {
+	now := time.Now()
+	sendTimes, sendBytes, recvTimes, recvBytes := t.read()
+
+	elapsed := now.Sub(t.lastSnapshot).Seconds()
+	sendMB, recvMB := 0.0, 0.0
+	if elapsed > 0 {
+		sendMB = float64(sendBytes-t.lastSend) / elapsed / (1024 * 1024)
+		recvMB = float64(recvBytes-t.lastRecv) / elapsed / (1024 * 1024)
+	}
+
+	t.lastSnapshot = now
+	t.lastSend = sendBytes
+	t.lastRecv = recvBytes
+
+	return RateSnapshot{
+		SendMBPerSec: sendMB,
+		RecvMBPerSec: recvMB,
+		Inflight:     sendTimes - recvTimes,
+		SendTimes:    sendTimes,
+		RecvTimes:    recvTimes,
+	}
+}
@@ -0,0 +1,78 @@
+package livestats
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyStatsSnapshot(t *testing.T) {
+	s := NewLatencyStats()
+	samples := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		40 * time.Millisecond,
+	}
+	for _, d := range samples {
+		s.Record(d)
+	}
+
+	snap := s.Snapshot()
+	if snap.Count != int64(len(samples)) {
+		t.Fatalf("Count = %d, want %d", snap.Count, len(samples))
+	}
+	if want := 25 * time.Millisecond; snap.Mean != want {
+		t.Fatalf("Mean = %v, want %v", snap.Mean, want)
+	}
+	if want := samples[0]; snap.Min != want {
+		t.Fatalf("Min = %v, want %v", snap.Min, want)
+	}
+	if want := samples[len(samples)-1]; snap.Max != want {
+		t.Fatalf("Max = %v, want %v", snap.Max, want)
+	}
+
+	// Sample standard deviation for {10,20,30,40}ms, i.e. with Bessel's
+	// correction (n-1), as LatencyStats computes: sqrt(500/3)ms.
+	wantStdDev := time.Duration(math.Sqrt(500.0/3.0) * float64(time.Millisecond))
+	if diff := snap.StdDev - wantStdDev; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Fatalf("StdDev = %v, want ~%v", snap.StdDev, wantStdDev)
+	}
+}
+
+func TestLatencyStatsCumulativeAcrossSnapshots(t *testing.T) {
+	s := NewLatencyStats()
+	s.Record(5 * time.Millisecond)
+	first := s.Snapshot()
+	if first.Count != 1 {
+		t.Fatalf("Count = %d, want 1", first.Count)
+	}
+
+	s.Record(15 * time.Millisecond)
+	second := s.Snapshot()
+	if second.Count != 2 {
+		t.Fatalf("Count = %d, want 2", second.Count)
+	}
+	// Cumulative mean folds in both samples even though the window reset.
+	if want := 10 * time.Millisecond; second.Mean != want {
+		t.Fatalf("Mean = %v, want %v", second.Mean, want)
+	}
+}
+
+func TestRateTrackerSnapshot(t *testing.T) {
+	var sendTimes, sendBytes, recvTimes, recvBytes int64
+	tracker := NewRateTracker(func() (int64, int64, int64, int64) {
+		return sendTimes, sendBytes, recvTimes, recvBytes
+	})
+
+	sendTimes, sendBytes = 10, 1024
+	recvTimes, recvBytes = 8, 512
+	snap := tracker.Snapshot()
+
+	if snap.Inflight != sendTimes-recvTimes {
+		t.Fatalf("Inflight = %d, want %d", snap.Inflight, sendTimes-recvTimes)
+	}
+	if snap.SendTimes != sendTimes || snap.RecvTimes != recvTimes {
+		t.Fatalf("SendTimes/RecvTimes = %d/%d, want %d/%d", snap.SendTimes, snap.RecvTimes, sendTimes, recvTimes)
+	}
+}
@@ -0,0 +1,288 @@
+// Command mwsbench drives one of the mwsbench/bench* runners against a
+// frameworks/* server and prints (and optionally persists) its report.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-websocket-benchmark/config"
+	"go-websocket-benchmark/frameworks/fake"
+	"go-websocket-benchmark/logging"
+	"go-websocket-benchmark/mwsbench/benchecho"
+	"go-websocket-benchmark/mwsbench/benchrate"
+	"go-websocket-benchmark/mwsbench/netsim"
+	"go-websocket-benchmark/mwsbench/profiling"
+	"go-websocket-benchmark/mwsbench/protocol"
+	"go-websocket-benchmark/mwsbench/report"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/lesismal/nbio/nbhttp"
+	nbws "github.com/lesismal/nbio/nbhttp/websocket"
+)
+
+var (
+	framework   = flag.String("framework", config.NbioStd, "framework to benchmark, see config.GetFrameworkServerAddrs")
+	ip          = flag.String("ip", "127.0.0.1", "ip of the framework server")
+	mode        = flag.String("mode", "echo", "echo or rate")
+	connections = flag.Int("conns", 1000, "number of websocket connections to open")
+	concurrency = flag.Int("c", 0, "concurrency, 0 = let the benchmark decide")
+	total       = flag.Int("n", 100000, "total requests for -mode=echo")
+	duration    = flag.Duration("duration", 10*time.Second, "run duration for -mode=rate")
+	payload     = flag.String("b", "1024", "payload size(s) in bytes; comma-separated to sweep a matrix, e.g. -b=64,1024,4096,65536,1048576")
+	sendRate    = flag.String("sendRate", "1", "messages/sec per connection for -mode=rate; comma-separated to sweep a matrix, e.g. -sendRate=1,10,100")
+	cooldown    = flag.Duration("cooldown", 0, "pause between sweep cells when -b or -sendRate has more than one value")
+	resultFile  = flag.String("resultFile", "", "path to append this run's report(s) to, see mwsbench/benchresult")
+
+	compression          = flag.String("compression", "off", "permessage-deflate mode: off, deflate, deflate-context-takeover, deflate-no-context-takeover, see RFC 7692")
+	compressionLevel     = flag.Int("compressionLevel", 0, "compress/flate level while -compression is enabled, 0 = default")
+	compressionThreshold = flag.Int("compressionThreshold", 0, "skip compression for payloads smaller than this many bytes")
+	payloadEntropy       = flag.Float64("payloadEntropy", 1, "how compressible the generated payload is: 0 = all one byte repeated, 1 = fully random")
+
+	cpuProfile      = flag.String("cpuProfile", "", "write a client-side CPU profile to this path")
+	memProfile      = flag.String("memProfile", "", "write a client-side heap profile to this path")
+	blockProfile    = flag.String("blockProfile", "", "write a client-side block profile to this path")
+	mutexProfile    = flag.String("mutexProfile", "", "write a client-side mutex profile to this path")
+	traceFile       = flag.String("trace", "", "write a client-side execution trace to this path")
+	memProfileRate  = flag.Int("memProfileRate", 0, "runtime.MemProfileRate while -memProfile is set, 0 = 4096")
+	profileOutDir   = flag.String("outDir", "", "directory to download the server's profiles into")
+	profileServer   = flag.Bool("profileServer", false, "also fetch a matching profile from the server under test (it must run with -debugPprof)")
+	profileDuration = flag.Duration("profileDuration", 0, "expected length of the measured phase, used to size the server's profile window for -mode=echo; -mode=rate uses -duration")
+
+	liveOutput   = flag.Bool("live", false, "redraw a live terminal dashboard during the measured phase")
+	liveInterval = flag.Duration("liveInterval", 200*time.Millisecond, "how often to redraw -live / publish -liveJSON")
+	liveJSON     = flag.String("liveJSON", "", "host:port to stream NDJSON snapshots of the live dashboard on")
+
+	networkMode = flag.String("networkMode", "local", "simulate network conditions on the dialed connection(s): local, lan, wan, mobile, or custom:rtt=80ms,jitter=10ms,bw=10Mbit,loss=0.1%")
+)
+
+func main() {
+	flag.Parse()
+
+	addrs, err := config.GetFrameworkServerAddrs(*framework)
+	if err != nil {
+		logging.Fatalf("mwsbench: %v", err)
+	}
+	addr := fmt.Sprintf("ws://%s%s/ws", *ip, addrs[0])
+
+	netCfg, err := netsim.ParseMode(*networkMode)
+	if err != nil {
+		logging.Fatalf("mwsbench: %v", err)
+	}
+	var shaper *netsim.Shaper
+	if netCfg.Enabled() {
+		shaper = netsim.NewShaper(netCfg)
+	}
+
+	switch strings.ToLower(*mode) {
+	case "echo":
+		runEcho(addr, shaper)
+	case "rate":
+		runRate(addr, shaper)
+	default:
+		logging.Fatalf("mwsbench: unknown -mode %q, want echo or rate", *mode)
+	}
+}
+
+// parseIntList splits a comma-separated flag value like "64,1024,4096"
+// into its ints, used by -b and -sendRate to drive a sweep matrix.
+func parseIntList(flagName, value string) []int {
+	parts := strings.Split(value, ",")
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			logging.Fatalf("mwsbench: bad -%s value %q: %v", flagName, value, err)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// profilingOptions builds the shared profiling.Options from flags. addr is
+// the ws://... URL dialed for the benchmark; debug/pprof lives on the same
+// host:port.
+func profilingOptions(addr string, duration time.Duration) profiling.Options {
+	opts := profiling.Options{
+		CPUProfile:     *cpuProfile,
+		MemProfile:     *memProfile,
+		BlockProfile:   *blockProfile,
+		MutexProfile:   *mutexProfile,
+		Trace:          *traceFile,
+		MemProfileRate: *memProfileRate,
+		OutDir:         *profileOutDir,
+		Duration:       duration,
+	}
+	if *profileServer {
+		u, err := url.Parse(addr)
+		if err != nil {
+			logging.Fatalf("mwsbench: bad -ip/-framework address %q: %v", addr, err)
+		}
+		opts.RemoteURL = "http://" + u.Host
+	}
+	return opts
+}
+
+func runEcho(addr string, shaper *netsim.Shaper) {
+	compress := protocol.Compression(*compression)
+	payloads := parseIntList("b", *payload)
+
+	dialer := *gorilla.DefaultDialer
+	dialer.EnableCompression = compress.Enabled()
+	switch {
+	case *framework == config.Fake:
+		dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			client, server := fake.Pipe()
+			go fake.ServeEcho(server)
+			if shaper != nil {
+				return shaper.Wrap(client), nil
+			}
+			return client, nil
+		}
+	case shaper != nil:
+		dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return shaper.Wrap(conn), nil
+		}
+	}
+
+	connsMap := make(map[*gorilla.Conn]struct{}, *connections)
+	for i := 0; i < *connections; i++ {
+		conn, _, err := dialer.Dial(addr, nil)
+		if err != nil {
+			logging.Fatalf("mwsbench: dial %v failed: %v", addr, err)
+		}
+		connsMap[conn] = struct{}{}
+	}
+
+	bm := benchecho.New(*framework, *total, *ip, connsMap)
+	bm.Concurrency = *concurrency
+	bm.Payloads = payloads
+	bm.Cooldown = *cooldown
+	bm.Compression = compress
+	bm.CompressionLevel = *compressionLevel
+	bm.CompressionThreshold = *compressionThreshold
+	bm.PayloadEntropy = *payloadEntropy
+	bm.Profiling = profilingOptions(addr, *profileDuration)
+	bm.LiveOutput = *liveOutput
+	bm.LiveInterval = *liveInterval
+	bm.LiveJSON = *liveJSON
+	bm.Run()
+
+	rpts := make([]report.BenchEchoReport, 0, len(bm.Reports()))
+	for _, r := range bm.Reports() {
+		rpt := r.(*report.BenchEchoReport)
+		printReport(rpt)
+		rpts = append(rpts, *rpt)
+	}
+
+	if *resultFile != "" {
+		rf := &report.ResultFile{Echo: rpts}
+		switch prev, err := report.LoadResultFile(*resultFile); {
+		case err == nil:
+			rf.Echo = append(prev.Echo, rf.Echo...)
+			rf.Rate = prev.Rate
+		case errors.Is(err, os.ErrNotExist):
+			// first run against this path, nothing to merge
+		default:
+			logging.Fatalf("mwsbench: %v", err)
+		}
+		if err := rf.Save(*resultFile); err != nil {
+			logging.Fatalf("mwsbench: %v", err)
+		}
+	}
+}
+
+func runRate(addr string, shaper *netsim.Shaper) {
+	compress := protocol.Compression(*compression)
+	payloads := parseIntList("b", *payload)
+	sendRates := parseIntList("sendRate", *sendRate)
+
+	// nbws.Dialer always dials a real OS socket itself (it has no
+	// conn-level dial hook, and its engine needs the connection's raw fd
+	// to register it for async I/O), so -networkMode shaping and
+	// -framework=fake, which both work by substituting a non-OS net.Conn,
+	// aren't reachable from -mode=rate the way they are from -mode=echo's
+	// gorilla dialer.
+	if shaper != nil {
+		logging.Fatalf("mwsbench: -networkMode is not supported for -mode=rate (nbio's client dials real sockets directly)")
+	}
+	if *framework == config.Fake {
+		logging.Fatalf("mwsbench: -framework=fake is not supported for -mode=rate (nbio's client dials real sockets directly)")
+	}
+
+	// nbws.Dialer needs a running Engine to hand each dialed connection
+	// off to for async I/O; with no Addrs it starts client-only, with no
+	// listeners.
+	engine := nbhttp.NewEngine(nbhttp.Config{})
+	if err := engine.Start(); err != nil {
+		logging.Fatalf("mwsbench: nbhttp.Engine.Start failed: %v", err)
+	}
+	defer engine.Stop()
+
+	connsMap := make(map[*nbws.Conn]struct{}, *connections)
+	dialer := &nbws.Dialer{Engine: engine, Options: nbws.NewOptions(), EnableCompression: compress.Enabled()}
+	for i := 0; i < *connections; i++ {
+		conn, _, err := dialer.Dial(addr, nil)
+		if err != nil {
+			logging.Fatalf("mwsbench: dial %v failed: %v", addr, err)
+		}
+		connsMap[conn] = struct{}{}
+	}
+
+	bm := benchrate.New(*framework, *ip, connsMap)
+	bm.Duration = *duration
+	bm.Concurrency = *concurrency
+	bm.Payloads = payloads
+	bm.SendRates = sendRates
+	bm.Cooldown = *cooldown
+	bm.Compression = compress
+	bm.CompressionLevel = *compressionLevel
+	bm.CompressionThreshold = *compressionThreshold
+	bm.PayloadEntropy = *payloadEntropy
+	bm.Profiling = profilingOptions(addr, *duration)
+	bm.LiveOutput = *liveOutput
+	bm.LiveInterval = *liveInterval
+	bm.LiveJSON = *liveJSON
+	bm.Run()
+
+	rpts := make([]report.BenchRateReport, 0, len(bm.Reports()))
+	for _, r := range bm.Reports() {
+		rpt := r.(*report.BenchRateReport)
+		printReport(rpt)
+		rpts = append(rpts, *rpt)
+	}
+
+	if *resultFile != "" {
+		rf := &report.ResultFile{Rate: rpts}
+		switch prev, err := report.LoadResultFile(*resultFile); {
+		case err == nil:
+			rf.Rate = append(prev.Rate, rf.Rate...)
+			rf.Echo = prev.Echo
+		case errors.Is(err, os.ErrNotExist):
+			// first run against this path, nothing to merge
+		default:
+			logging.Fatalf("mwsbench: %v", err)
+		}
+		if err := rf.Save(*resultFile); err != nil {
+			logging.Fatalf("mwsbench: %v", err)
+		}
+	}
+}
+
+func printReport(r report.Report) {
+	fmt.Println("| " + strings.Join(r.Headers(), " | ") + " |")
+	fmt.Println("| " + strings.Repeat("--- | ", len(r.Headers())))
+	fmt.Println("| " + strings.Join(r.Values(), " | ") + " |")
+}
@@ -0,0 +1,127 @@
+// Package netsim wraps a net.Conn so a benchmark can measure a framework
+// under simulated WAN conditions instead of loopback, where write
+// batching, coalescing, and backpressure behavior is largely hidden by how
+// fast the kernel can shuttle bytes between two local sockets.
+package netsim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config describes the conditions a Shaper applies to every Conn it wraps.
+type Config struct {
+	// RTT is the simulated round-trip time; each Read/Write completion is
+	// delayed by roughly RTT/2 plus jitter, so a request/response pair
+	// sees the full RTT.
+	RTT time.Duration
+	// Jitter is the standard deviation applied to RTT/2 per operation via
+	// rand.NormFloat64.
+	Jitter time.Duration
+	// Bandwidth caps throughput in bytes/sec, shared across every Conn a
+	// Shaper wraps, in each direction independently. 0 means unlimited.
+	Bandwidth int64
+	// Loss is the probability, in [0,1], that a given Read is truncated to
+	// simulate a dropped segment: it returns half the bytes it read and the
+	// other half is discarded. The cut is not aligned to websocket frame
+	// boundaries.
+	Loss float64
+}
+
+// namedModes are the -networkMode presets, loosely modeled on typical
+// last-mile conditions so a suite can sanity-check framework behavior
+// without having to hand-tune a custom: config.
+var namedModes = map[string]Config{
+	"local":  {},
+	"lan":    {RTT: time.Millisecond, Jitter: 200 * time.Microsecond, Bandwidth: 1_000_000_000 / 8},
+	"wan":    {RTT: 80 * time.Millisecond, Jitter: 10 * time.Millisecond, Bandwidth: 10_000_000 / 8, Loss: 0.001},
+	"mobile": {RTT: 150 * time.Millisecond, Jitter: 40 * time.Millisecond, Bandwidth: 2_000_000 / 8, Loss: 0.02},
+}
+
+// ParseMode parses a -networkMode value: one of the named presets above,
+// or "custom:rtt=80ms,jitter=10ms,bw=10Mbit,loss=0.1%". bw accepts a
+// plain byte/sec integer or a "<N>Kbit"/"<N>Mbit"/"<N>Gbit" suffix; loss
+// accepts a plain fraction ("0.02") or a percentage ("2%").
+func ParseMode(s string) (Config, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Config{}, nil
+	}
+	if cfg, ok := namedModes[strings.ToLower(s)]; ok {
+		return cfg, nil
+	}
+	rest, ok := strings.CutPrefix(s, "custom:")
+	if !ok {
+		return Config{}, fmt.Errorf("netsim: unknown -networkMode %q, want local, lan, wan, mobile, or custom:...", s)
+	}
+
+	var cfg Config
+	for _, field := range strings.Split(rest, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(field, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("netsim: bad custom field %q, want key=value", field)
+		}
+		var err error
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "rtt":
+			cfg.RTT, err = time.ParseDuration(v)
+		case "jitter":
+			cfg.Jitter, err = time.ParseDuration(v)
+		case "bw":
+			cfg.Bandwidth, err = parseBandwidth(v)
+		case "loss":
+			cfg.Loss, err = parseFraction(v)
+		default:
+			err = fmt.Errorf("unknown field %q", k)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("netsim: custom field %q: %w", field, err)
+		}
+	}
+	return cfg, nil
+}
+
+// parseBandwidth accepts a plain byte/sec integer or an N<Kbit|Mbit|Gbit>
+// bit/sec value, returning bytes/sec.
+func parseBandwidth(v string) (int64, error) {
+	v = strings.TrimSpace(v)
+	for suffix, bitsPerUnit := range map[string]int64{
+		"gbit": 1_000_000_000,
+		"mbit": 1_000_000,
+		"kbit": 1_000,
+	} {
+		if rest, ok := strings.CutSuffix(strings.ToLower(v), suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+			if err != nil {
+				return 0, err
+			}
+			return int64(n * float64(bitsPerUnit) / 8), nil
+		}
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// parseFraction accepts a plain fraction ("0.02") or a percentage ("2%").
+func parseFraction(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	if rest, ok := strings.CutSuffix(v, "%"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+		if err != nil {
+			return 0, err
+		}
+		return n / 100, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// Enabled reports whether cfg simulates anything at all, so callers can
+// skip wrapping entirely on the (default) local/unset path.
+func (cfg Config) Enabled() bool {
+	return cfg.RTT > 0 || cfg.Jitter > 0 || cfg.Bandwidth > 0 || cfg.Loss > 0
+}
@@ -0,0 +1,207 @@
+package netsim
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Shaper applies a Config to every Conn it Wraps: a shared bandwidth
+// budget per direction (so N conns through one Shaper contend for the
+// same simulated link, the way they would behind a real bottleneck), a
+// per-operation RTT/jitter delay drained off a single release-time heap,
+// and packet loss simulated as short reads.
+type Shaper struct {
+	cfg Config
+
+	sendLimiter *rate.Limiter
+	recvLimiter *rate.Limiter
+
+	delays *delayQueue
+}
+
+// NewShaper builds a Shaper from cfg. Bandwidth, if set, is enforced with
+// a token bucket burstable to one second's worth of traffic.
+func NewShaper(cfg Config) *Shaper {
+	s := &Shaper{cfg: cfg, delays: newDelayQueue()}
+	if cfg.Bandwidth > 0 {
+		s.sendLimiter = rate.NewLimiter(rate.Limit(cfg.Bandwidth), int(cfg.Bandwidth))
+		s.recvLimiter = rate.NewLimiter(rate.Limit(cfg.Bandwidth), int(cfg.Bandwidth))
+	}
+	return s
+}
+
+// Wrap returns conn shaped by s. s must not be nil: the wrapped conn
+// dereferences it on every Read and Write. With a Config that Enabled
+// reports false the wrapped conn adds no delay, bandwidth cap or loss,
+// and the callers in this repo skip wrapping on that path.
+func (s *Shaper) Wrap(conn net.Conn) net.Conn {
+	return &shapedConn{Conn: conn, shaper: s}
+}
+
+// halfRTT returns RTT/2 plus normally-distributed jitter, floored at 0.
+func (s *Shaper) halfRTT() time.Duration {
+	d := s.cfg.RTT / 2
+	if s.cfg.Jitter > 0 {
+		d += time.Duration(rand.NormFloat64() * float64(s.cfg.Jitter))
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// delay blocks the caller until s's release-time heap says its turn has
+// come, i.e. for roughly halfRTT from now; calls already queued ahead of
+// it don't push its release back. Using one heap+goroutine per Shaper instead of one timer per
+// call keeps a sweep with thousands of connections from spawning a timer
+// per Read/Write.
+func (s *Shaper) delay() {
+	if s.cfg.RTT == 0 && s.cfg.Jitter == 0 {
+		return
+	}
+	s.delays.wait(time.Now().Add(s.halfRTT()))
+}
+
+type shapedConn struct {
+	net.Conn
+	shaper *Shaper
+}
+
+func (c *shapedConn) Read(b []byte) (int, error) {
+	c.shaper.delay()
+	if c.shaper.recvLimiter != nil {
+		if err := waitBandwidth(c.shaper.recvLimiter, len(b)); err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Read(b)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	if c.shaper.cfg.Loss > 0 && rand.Float64() < c.shaper.cfg.Loss {
+		// Simulate a dropped segment by truncating this read rather than
+		// failing it outright: only the first half of the bytes read is
+		// returned and the other half is discarded. The cut is at the
+		// midpoint of this read, not at a websocket frame boundary.
+		n /= 2
+	}
+	return n, nil
+}
+
+func (c *shapedConn) Write(b []byte) (int, error) {
+	c.shaper.delay()
+	if c.shaper.sendLimiter != nil {
+		if err := waitBandwidth(c.shaper.sendLimiter, len(b)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+// waitBandwidth pays the token cost of n bytes against limiter, one
+// burst-sized chunk at a time. WaitN errors out if asked for more than the
+// limiter's burst in one call, so a single I/O larger than one second's
+// worth of bandwidth (common with -mode=echo's larger -b sizes, or a
+// low-bandwidth -networkMode like mobile) has to be split into multiple
+// waits rather than clamped to one — otherwise the caller would only pay
+// for `burst` bytes of delay while transmitting the whole buffer.
+func waitBandwidth(limiter *rate.Limiter, n int) error {
+	burst := limiter.Burst()
+	for n > 0 {
+		chunk := n
+		if chunk > burst {
+			chunk = burst
+		}
+		if err := limiter.WaitN(context.Background(), chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// delayQueue is a min-heap of pending releases, keyed on release time and
+// drained by a single goroutine so Shaper.delay doesn't spin up a timer
+// per call.
+type delayQueue struct {
+	mu    sync.Mutex
+	items delayHeap
+	wake  chan struct{}
+}
+
+func newDelayQueue() *delayQueue {
+	q := &delayQueue{wake: make(chan struct{}, 1)}
+	go q.run()
+	return q
+}
+
+// wait blocks until release has passed.
+func (q *delayQueue) wait(release time.Time) {
+	done := make(chan struct{})
+	q.mu.Lock()
+	heap.Push(&q.items, &delayItem{release: release, done: done})
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	<-done
+}
+
+func (q *delayQueue) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		q.mu.Lock()
+		for len(q.items) > 0 && !time.Now().Before(q.items[0].release) {
+			item := heap.Pop(&q.items).(*delayItem)
+			close(item.done)
+		}
+		var wait time.Duration = time.Hour
+		if len(q.items) > 0 {
+			wait = time.Until(q.items[0].release)
+			if wait < 0 {
+				wait = 0
+			}
+		}
+		q.mu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-q.wake:
+		}
+	}
+}
+
+type delayItem struct {
+	release time.Time
+	done    chan struct{}
+}
+
+type delayHeap []*delayItem
+
+func (h delayHeap) Len() int           { return len(h) }
+func (h delayHeap) Less(i, j int) bool { return h[i].release.Before(h[j].release) }
+func (h delayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap) Push(x any)        { *h = append(*h, x.(*delayItem)) }
+func (h *delayHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
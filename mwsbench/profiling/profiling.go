@@ -0,0 +1,204 @@
+// Package profiling starts a local runtime/pprof capture on the benchmark
+// client for the duration of a measured phase, and/or downloads a matching
+// profile from a server under test that has registered /debug/pprof/*
+// behind frameworks.HandleCommon's -debugPprof flag. It's shared by
+// BenchEcho and BenchRate so both runners attribute latency or CPU
+// regressions the same way.
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"go-websocket-benchmark/logging"
+)
+
+// Options configures a profiling Session. Empty string fields disable that
+// particular capture.
+type Options struct {
+	CPUProfile     string // local client CPU profile output path
+	MemProfile     string // local client heap profile output path
+	BlockProfile   string // local client block profile output path
+	MutexProfile   string // local client mutex profile output path
+	Trace          string // local client execution trace output path
+	MemProfileRate int    // runtime.MemProfileRate while MemProfile is set
+
+	// RemoteURL, when set, is the base "http://host:port" of a server
+	// whose frameworks.HandleCommon was started with -debugPprof, used to
+	// fetch profile/heap/block/mutex/trace into OutDir.
+	RemoteURL string
+	OutDir    string
+	// Duration is the expected length of the measured phase. It's used to
+	// size the server's ?seconds= window for the CPU profile and trace,
+	// which are long-polled starting from Start rather than Stop.
+	Duration time.Duration
+}
+
+func (o Options) enabled() bool {
+	return o.CPUProfile != "" || o.MemProfile != "" || o.BlockProfile != "" ||
+		o.MutexProfile != "" || o.Trace != "" || o.RemoteURL != ""
+}
+
+// Session is a started profiling capture; call Stop when the measured
+// phase ends.
+type Session struct {
+	opts Options
+	wg   sync.WaitGroup
+}
+
+// Start begins the local captures configured in opts, and, if RemoteURL is
+// set, kicks off the long-polled remote CPU profile and trace downloads
+// (which must start now to cover the measured phase). It's a no-op (but
+// still safe to Stop) when opts is the zero value.
+func Start(opts Options) *Session {
+	s := &Session{opts: opts}
+	if !opts.enabled() {
+		return s
+	}
+
+	if opts.CPUProfile != "" {
+		f, err := os.Create(opts.CPUProfile)
+		if err != nil {
+			logging.Fatalf("profiling: create cpu profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			logging.Fatalf("profiling: start cpu profile: %v", err)
+		}
+	}
+	if opts.BlockProfile != "" {
+		runtime.SetBlockProfileRate(1)
+	}
+	if opts.MutexProfile != "" {
+		runtime.SetMutexProfileFraction(1)
+	}
+	if opts.MemProfile != "" {
+		rate := opts.MemProfileRate
+		if rate <= 0 {
+			rate = 4096
+		}
+		runtime.MemProfileRate = rate
+	}
+	if opts.Trace != "" {
+		f, err := os.Create(opts.Trace)
+		if err != nil {
+			logging.Fatalf("profiling: create trace: %v", err)
+		}
+		if err := trace.Start(f); err != nil {
+			logging.Fatalf("profiling: start trace: %v", err)
+		}
+	}
+
+	if opts.RemoteURL != "" {
+		if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+			logging.Printf("profiling: mkdir %v: %v", opts.OutDir, err)
+		} else {
+			s.fetchRemoteLongPoll()
+		}
+	}
+
+	return s
+}
+
+// Stop ends local captures and, if RemoteURL is set, waits for the
+// long-polled downloads started in Start and fetches the remaining
+// (instantaneous) server-side profiles.
+func (s *Session) Stop() {
+	opts := s.opts
+	if !opts.enabled() {
+		return
+	}
+
+	if opts.CPUProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if opts.Trace != "" {
+		trace.Stop()
+	}
+	if opts.MemProfile != "" {
+		writeProfile("heap", opts.MemProfile)
+	}
+	if opts.BlockProfile != "" {
+		writeProfile("block", opts.BlockProfile)
+		runtime.SetBlockProfileRate(0)
+	}
+	if opts.MutexProfile != "" {
+		writeProfile("mutex", opts.MutexProfile)
+		runtime.SetMutexProfileFraction(0)
+	}
+
+	if opts.RemoteURL != "" {
+		s.wg.Wait()
+		for _, name := range []string{"heap", "block", "mutex"} {
+			if err := downloadTo(opts.RemoteURL+"/debug/pprof/"+name, filepath.Join(opts.OutDir, "server-"+name+".prof")); err != nil {
+				logging.Printf("profiling: fetch %v profile: %v", name, err)
+			}
+		}
+	}
+}
+
+func writeProfile(name, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		logging.Printf("profiling: create %v profile: %v", name, err)
+		return
+	}
+	defer f.Close()
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		logging.Printf("profiling: write %v profile: %v", name, err)
+	}
+}
+
+// fetchRemoteLongPoll kicks off the CPU profile and trace downloads, which
+// block on the server for roughly opts.Duration, so they need to start
+// alongside the local measured phase rather than after it.
+func (s *Session) fetchRemoteLongPoll() {
+	seconds := int(s.opts.Duration.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	longPolled := []string{"profile", "trace"}
+	for _, name := range longPolled {
+		name := name
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			url := fmt.Sprintf("%s/debug/pprof/%s?seconds=%d", s.opts.RemoteURL, name, seconds)
+			outName := name
+			if name == "profile" {
+				outName = "cpu"
+			}
+			if err := downloadTo(url, filepath.Join(s.opts.OutDir, "server-"+outName+".prof")); err != nil {
+				logging.Printf("profiling: fetch %v profile: %v", name, err)
+			}
+		}()
+	}
+}
+
+func downloadTo(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v", url, resp.Status)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
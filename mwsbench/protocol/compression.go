@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+
+	"go-websocket-benchmark/logging"
+)
+
+// Compression selects an RFC 7692 permessage-deflate mode for a benchmark
+// run. The *-context-takeover variants only matter for servers/clients that
+// keep a sliding window across messages. All three enabled modes behave
+// the same here: EncodeClientMessageDeflate always resets its compressor
+// per message, and the mwsbench dialers only request permessage-deflate
+// on or off, so the choice changes neither the bytes on the wire nor the
+// negotiated extension parameters.
+type Compression string
+
+const (
+	CompressionOff                      Compression = "off"
+	CompressionDeflate                  Compression = "deflate"
+	CompressionDeflateContextTakeover   Compression = "deflate-context-takeover"
+	CompressionDeflateNoContextTakeover Compression = "deflate-no-context-takeover"
+)
+
+// Enabled reports whether c requests permessage-deflate at all.
+func (c Compression) Enabled() bool {
+	return c != "" && c != CompressionOff
+}
+
+// NoContextTakeover reports whether c asks peers not to keep a sliding
+// compression window across messages.
+func (c Compression) NoContextTakeover() bool {
+	return c == CompressionDeflateNoContextTakeover
+}
+
+// EncodeClientMessageDeflate is EncodeClientMessage plus RFC 7692
+// permessage-deflate: payload is deflated, the 4-byte empty-block trailer
+// flate always appends is stripped, and RSV1 is set so a conforming server
+// decompresses it. It returns the frame and the number of deflated payload
+// bytes actually placed on the wire, so callers can report wire bytes
+// distinct from logical payload bytes.
+func EncodeClientMessageDeflate(messageType int, payload []byte, level int) (frame []byte, wireBytes int) {
+	compressed := Deflate(payload, level)
+	return encodeFrame(messageType, compressed, true), len(compressed)
+}
+
+// Deflate compresses data with compress/flate at level (flate.DefaultCompression
+// if level is 0) and strips the trailing 0x00 0x00 0xff 0xff empty
+// deflate block that RFC 7692 has the peer re-add before inflating.
+func Deflate(data []byte, level int) []byte {
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		logging.Fatalf("protocol: flate.NewWriter: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		logging.Fatalf("protocol: flate write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		logging.Fatalf("protocol: flate close: %v", err)
+	}
+	out := buf.Bytes()
+	return bytes.TrimSuffix(out, []byte{0x00, 0x00, 0xff, 0xff})
+}
+
+// Inflate reverses Deflate, re-appending the trailer flate expects.
+func Inflate(data []byte) ([]byte, error) {
+	data = append(data, 0x00, 0x00, 0xff, 0xff)
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
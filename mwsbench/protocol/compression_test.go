@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeflateInflateRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"repeated byte", bytes.Repeat([]byte{0x00}, 4096)},
+		{"random-ish", GeneratePayload(4096, 1)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			compressed := Deflate(tc.data, 0)
+			got, err := Inflate(compressed)
+			if err != nil {
+				t.Fatalf("Inflate: %v", err)
+			}
+			if !bytes.Equal(got, tc.data) {
+				t.Fatalf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(tc.data))
+			}
+		})
+	}
+}
+
+func TestEncodeClientMessageDeflateReportsWireBytes(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x00}, 4096)
+	frame, wireBytes := EncodeClientMessageDeflate(1, payload, 0)
+	if wireBytes <= 0 || wireBytes >= len(payload) {
+		t.Fatalf("wireBytes = %d, want a compressed size between 0 and %d", wireBytes, len(payload))
+	}
+	if len(frame) == 0 {
+		t.Fatalf("frame is empty")
+	}
+}
+
+func TestCompressionEnabled(t *testing.T) {
+	cases := []struct {
+		c    Compression
+		want bool
+	}{
+		{CompressionOff, false},
+		{"", false},
+		{CompressionDeflate, true},
+		{CompressionDeflateContextTakeover, true},
+		{CompressionDeflateNoContextTakeover, true},
+	}
+	for _, tc := range cases {
+		if got := tc.c.Enabled(); got != tc.want {
+			t.Errorf("Compression(%q).Enabled() = %v, want %v", tc.c, got, tc.want)
+		}
+	}
+}
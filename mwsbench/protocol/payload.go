@@ -0,0 +1,31 @@
+package protocol
+
+import "math/rand"
+
+// GeneratePayload fills a size-byte buffer whose compressibility is
+// controlled by entropy, clamped to [0,1]: 0 produces a buffer of a single
+// byte repeated (trivially compressible, representative of e.g. padded or
+// templated traffic), 1 produces fully random bytes (incompressible,
+// representative of encrypted/already-compressed traffic), and values in
+// between linearly blend the two so -payloadEntropy can sweep realistic
+// compression ratios.
+func GeneratePayload(size int, entropy float64) []byte {
+	if entropy < 0 {
+		entropy = 0
+	}
+	if entropy > 1 {
+		entropy = 1
+	}
+
+	buf := make([]byte, size)
+	rand.Read(buf)
+	if entropy >= 1 {
+		return buf
+	}
+
+	randomBytes := int(float64(size) * entropy)
+	for i := randomBytes; i < size; i++ {
+		buf[i] = buf[0]
+	}
+	return buf
+}
@@ -0,0 +1,85 @@
+// Package protocol implements the small slice of RFC 6455 the benchmark
+// client needs to talk to a server without paying gorilla/websocket's
+// per-call overhead on the hot path.
+package protocol
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// EncodeClientMessage frames payload as a masked client-to-server websocket
+// message of the given type, ready to be written directly to the
+// underlying net.Conn.
+func EncodeClientMessage(messageType int, payload []byte) []byte {
+	return encodeFrame(messageType, payload, false)
+}
+
+// encodeFrame builds a masked client-to-server frame around payload,
+// setting RSV1 (the permessage-deflate bit, RFC 7692 section 7.2.3) when
+// compressed is true.
+func encodeFrame(messageType int, payload []byte, compressed bool) []byte {
+	length := len(payload)
+
+	b0 := byte(0x80) | byte(messageType&0x0f) // fin + opcode
+	if compressed {
+		b0 |= 0x40 // RSV1
+	}
+
+	var header []byte
+	switch {
+	case length <= 125:
+		header = []byte{b0, byte(length) | 0x80}
+	case length <= 65535:
+		header = []byte{b0, 126 | 0x80, byte(length >> 8), byte(length)}
+	default:
+		header = make([]byte, 10)
+		header[0] = b0
+		header[1] = 127 | 0x80
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	var mask [4]byte
+	rand.Read(mask[:])
+	header = append(header, mask[:]...)
+
+	frame := make([]byte, 0, len(header)+length)
+	frame = append(frame, header...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// BatchBuffers repeats message so that sustaining sendRate messages/sec
+// doesn't require a ticker faster than the OS can reliably schedule: the
+// batch grows one message at a time until the tick rate is at most 1000/sec,
+// stopping early at maxBytes worth of messages (at least one) or at sendRate
+// messages. It returns the concatenated batch, how many messages it
+// contains (batch), and how many times per second it is written
+// (tickRate, sendRate/batch rounded down, at least 1).
+func BatchBuffers(message []byte, sendRate int, maxBytes int) (batchBuffer []byte, batch int, tickRate int) {
+	if sendRate <= 0 {
+		sendRate = 1
+	}
+	maxBatch := maxBytes / len(message)
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+
+	batch = 1
+	tickRate = sendRate
+	for tickRate > 1000 && batch < maxBatch && batch < sendRate {
+		batch++
+		tickRate = sendRate / batch
+	}
+	if tickRate < 1 {
+		tickRate = 1
+	}
+
+	batchBuffer = make([]byte, 0, len(message)*batch)
+	for i := 0; i < batch; i++ {
+		batchBuffer = append(batchBuffer, message...)
+	}
+	return batchBuffer, batch, tickRate
+}
@@ -0,0 +1,155 @@
+// Package report defines the result types produced by BenchEcho and
+// BenchRate runs, how they render as markdown table rows, and how a run's
+// results are persisted to disk so they can be compared across machines
+// and branches (see ResultFile and mwsbench/benchresult).
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// Report is implemented by every benchmark result so callers that don't
+// care about the concrete kind (e.g. the markdown table printer) can render
+// it generically.
+type Report interface {
+	// Headers returns the markdown table column titles for this report kind.
+	Headers() []string
+	// Values returns this report's row, formatted to match Headers.
+	Values() []string
+}
+
+type BenchEchoReport struct {
+	Framework   string
+	Connections int
+	Concurrency int
+	Payload     int
+	// WireBytes is the mean request payload size as it goes out on the
+	// wire: the deflated size when permessage-deflate applies (compression
+	// enabled and Payload at least the compression threshold), which is
+	// smaller than Payload for compressible payloads, and equal to Payload
+	// otherwise. Frame headers are not counted.
+	WireBytes int
+	Total     int
+	Success   int64
+	Failed    int64
+	Used      int64
+
+	CPUMin float64
+	CPUAvg float64
+	CPUMax float64
+
+	MEMRSSMin int64
+	MEMRSSAvg int64
+	MEMRSSMax int64
+
+	TPS  float64
+	Min  int64
+	Avg  int64
+	Max  int64
+	TP50 int64
+	TP75 int64
+	TP90 int64
+	TP95 int64
+	TP99 int64
+}
+
+type BenchRateReport struct {
+	Framework   string
+	Duration    int64
+	Connections int
+	SendRate    int
+	Payload     int
+
+	SendTimes int64
+	SendBytes int64
+	RecvTimes int64
+	RecvBytes int64
+
+	// WireSendBytes/WireRecvBytes count payload bytes as they go over the
+	// wire: deflated when permessage-deflate applies (smaller than
+	// SendBytes/RecvBytes for compressible payloads) and equal to
+	// SendBytes/RecvBytes otherwise. Frame headers are not counted, and
+	// WireRecvBytes is estimated from RecvBytes with the sent payload's
+	// compression ratio.
+	WireSendBytes int64
+	WireRecvBytes int64
+
+	CPUMin float64
+	CPUAvg float64
+	CPUMax float64
+
+	MEMRSSMin int64
+	MEMRSSAvg int64
+	MEMRSSMax int64
+}
+
+func (r *BenchRateReport) Headers() []string {
+	return []string{
+		"Framework", "Duration", "Conns", "SendRate", "Payload",
+		"Send/s", "Send MB/s", "Wire Send MB/s", "Recv/s", "Recv MB/s", "Wire Recv MB/s",
+		"CPU Avg", "CPU Max", "RSS Avg", "RSS Max",
+	}
+}
+
+func (r *BenchRateReport) Values() []string {
+	seconds := time.Duration(r.Duration).Seconds()
+	sendPerSec, recvPerSec := 0.0, 0.0
+	sendMBPerSec, recvMBPerSec := 0.0, 0.0
+	wireSendMBPerSec, wireRecvMBPerSec := 0.0, 0.0
+	if seconds > 0 {
+		sendPerSec = float64(r.SendTimes) / seconds
+		recvPerSec = float64(r.RecvTimes) / seconds
+		sendMBPerSec = float64(r.SendBytes) / seconds / (1024 * 1024)
+		recvMBPerSec = float64(r.RecvBytes) / seconds / (1024 * 1024)
+		wireSendMBPerSec = float64(r.WireSendBytes) / seconds / (1024 * 1024)
+		wireRecvMBPerSec = float64(r.WireRecvBytes) / seconds / (1024 * 1024)
+	}
+	return []string{
+		r.Framework,
+		time.Duration(r.Duration).String(),
+		fmt.Sprintf("%d", r.Connections),
+		fmt.Sprintf("%d", r.SendRate),
+		fmt.Sprintf("%d", r.Payload),
+		fmt.Sprintf("%.2f", sendPerSec),
+		fmt.Sprintf("%.2f", sendMBPerSec),
+		fmt.Sprintf("%.2f", wireSendMBPerSec),
+		fmt.Sprintf("%.2f", recvPerSec),
+		fmt.Sprintf("%.2f", recvMBPerSec),
+		fmt.Sprintf("%.2f", wireRecvMBPerSec),
+		fmt.Sprintf("%.2f", r.CPUAvg),
+		fmt.Sprintf("%.2f", r.CPUMax),
+		fmt.Sprintf("%d", r.MEMRSSAvg),
+		fmt.Sprintf("%d", r.MEMRSSMax),
+	}
+}
+
+func (r *BenchEchoReport) Headers() []string {
+	return []string{
+		"Framework", "Conns", "Concurrency", "Payload", "Wire Bytes", "Total", "Success", "Failed",
+		"TPS", "Avg", "TP50", "TP90", "TP95", "TP99", "CPU Avg", "CPU Max", "RSS Avg", "RSS Max",
+	}
+}
+
+func (r *BenchEchoReport) Values() []string {
+	return []string{
+		r.Framework,
+		fmt.Sprintf("%d", r.Connections),
+		fmt.Sprintf("%d", r.Concurrency),
+		fmt.Sprintf("%d", r.Payload),
+		fmt.Sprintf("%d", r.WireBytes),
+		fmt.Sprintf("%d", r.Total),
+		fmt.Sprintf("%d", r.Success),
+		fmt.Sprintf("%d", r.Failed),
+		fmt.Sprintf("%.2f", r.TPS),
+		fmt.Sprintf("%d", r.Avg),
+		fmt.Sprintf("%d", r.TP50),
+		fmt.Sprintf("%d", r.TP90),
+		fmt.Sprintf("%d", r.TP95),
+		fmt.Sprintf("%d", r.TP99),
+		fmt.Sprintf("%.2f", r.CPUAvg),
+		fmt.Sprintf("%.2f", r.CPUMax),
+		fmt.Sprintf("%d", r.MEMRSSAvg),
+		fmt.Sprintf("%d", r.MEMRSSMax),
+	}
+}
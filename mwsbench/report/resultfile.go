@@ -0,0 +1,51 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ResultFileVersion is bumped whenever ResultFile's schema changes in a way
+// that isn't backwards compatible, so benchresult can refuse to diff across
+// incompatible versions instead of silently comparing garbage.
+const ResultFileVersion = 1
+
+// ResultFile is the schema written by `-resultFile=path` and read back by
+// mwsbench/benchresult. It's plain JSON rather than a binary format so it stays
+// diffable and git-friendly, mirroring how gRPC's benchmain persists a
+// "base" featureparity.
+type ResultFile struct {
+	Version int               `json:"version"`
+	Echo    []BenchEchoReport `json:"echo,omitempty"`
+	Rate    []BenchRateReport `json:"rate,omitempty"`
+}
+
+// Save writes rf to path as indented JSON.
+func (rf *ResultFile) Save(path string) error {
+	rf.Version = ResultFileVersion
+	data, err := json.MarshalIndent(rf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("report: marshal result file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("report: write result file %q: %w", path, err)
+	}
+	return nil
+}
+
+// LoadResultFile reads back a file written by (*ResultFile).Save.
+func LoadResultFile(path string) (*ResultFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("report: read result file %q: %w", path, err)
+	}
+	rf := &ResultFile{}
+	if err := json.Unmarshal(data, rf); err != nil {
+		return nil, fmt.Errorf("report: parse result file %q: %w", path, err)
+	}
+	if rf.Version != ResultFileVersion {
+		return nil, fmt.Errorf("report: result file %q has version %d, want %d", path, rf.Version, ResultFileVersion)
+	}
+	return rf, nil
+}